@@ -0,0 +1 @@
+▶⬇Sql➡Up◀
@@ -1 +1 @@
-▶Sql➡Down◀
+▶⬇Sql➡Down◀
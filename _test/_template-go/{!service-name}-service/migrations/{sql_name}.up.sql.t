@@ -1 +0,0 @@
-▶Sql➡Up◀
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/vologzhan/maker"
+	"github.com/vologzhan/maker/source"
+	"github.com/vologzhan/maker/template"
+	"os"
+	"path/filepath"
+)
+
+const usage = `Usage:
+  maker upgrade -from <template> -to <template> [-path <project>]
+  maker explain -template <template> [-path <project>] <file>
+  maker report -template <template> [-path <project>]
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "upgrade":
+		err = upgrade(os.Args[2:])
+	case "explain":
+		err = explain(os.Args[2:])
+	case "report":
+		err = report(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func upgrade(args []string) error {
+	flags := flag.NewFlagSet("upgrade", flag.ExitOnError)
+	from := flags.String("from", "", "old template bundle, directory or zip")
+	to := flags.String("to", "", "new template bundle, directory or zip")
+	path := flags.String("path", ".", "project directory")
+	_ = flags.Parse(args)
+
+	if *from == "" || *to == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	oldTpl, err := openTemplate(*from)
+	if err != nil {
+		return err
+	}
+	newTpl, err := openTemplate(*to)
+	if err != nil {
+		return err
+	}
+
+	project, err := maker.New(oldTpl, *path)
+	if err != nil {
+		return err
+	}
+	printWarnings(project)
+
+	return maker.Upgrade(oldTpl, newTpl, *path)
+}
+
+func explain(args []string) error {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	tplPath := flags.String("template", "", "template bundle, directory or zip")
+	path := flags.String("path", ".", "project directory")
+	_ = flags.Parse(args)
+
+	if *tplPath == "" || flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	tpl, err := openTemplate(*tplPath)
+	if err != nil {
+		return err
+	}
+
+	filePath := flags.Arg(0)
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(*path, filePath)
+	}
+	relPath, err := filepath.Rel(*path, filePath)
+	if err != nil {
+		return err
+	}
+
+	tplFile, err := source.FindTemplateFile(tpl.Entrypoints[0].(*template.Dir), relPath)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	return source.Explain(os.Stdout, tplFile, content)
+}
+
+func report(args []string) error {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	tplPath := flags.String("template", "", "template bundle, directory or zip")
+	path := flags.String("path", ".", "project directory")
+	_ = flags.Parse(args)
+
+	if *tplPath == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	tpl, err := openTemplate(*tplPath)
+	if err != nil {
+		return err
+	}
+
+	root, err := maker.New(tpl, *path)
+	if err != nil {
+		return err
+	}
+	printWarnings(root)
+
+	items, err := root.Report()
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		fmt.Println(item.String())
+	}
+	if len(items) > 0 {
+		return fmt.Errorf("report: %d files and lines do not match the template", len(items))
+	}
+
+	return nil
+}
+
+func openTemplate(path string) (*template.Namespace, error) {
+	fsys, err := template.OpenBundle(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return template.New(fsys, "")
+}
+
+// printWarnings prints problems of the project, e.g. it was generated with an incompatible template version.
+func printWarnings(root *maker.Node) {
+	for _, warning := range root.Warnings() {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+}
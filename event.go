@@ -0,0 +1,75 @@
+package maker
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+)
+
+// Op is an operation on the node which listeners are notified about.
+type Op int
+
+const (
+	OpCreateChild Op = iota
+	OpSetValues
+	OpDelete
+	OpFlush
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpCreateChild:
+		return "CreateChild"
+	case OpSetValues:
+		return "SetValues"
+	case OpDelete:
+		return "Delete"
+	case OpFlush:
+		return "Flush"
+	default:
+		return fmt.Sprintf("Op(%d)", int(o))
+	}
+}
+
+// Event is passed to listeners before and after the operation on the node.
+// An error of a listener called before the operation cancels it. The operation is already applied
+// when listeners are called after it, their errors are returned with the result of the operation, nothing is rolled back.
+type Event struct {
+	Op        Op
+	After     bool
+	Node      *Node             // the parent before CreateChild, the created node after it
+	Namespace string            // namespace of the node the operation is applied to
+	Old       map[string]string // values before the operation, nil for CreateChild
+	New       map[string]string // values after the operation, nil for Delete and Flush
+}
+
+// Listener is called on operations on nodes of the project, see Options.Listeners.
+type Listener func(e Event) error
+
+// emit calls listeners of the project in order. Before the operation the first error stops it,
+// after the operation all listeners are called and their errors are joined.
+func (n *Node) emit(e Event) error {
+	if n.root().options.render {
+		return nil
+	}
+
+	var errs []error
+	for _, listener := range n.root().options.Listeners {
+		err := listener(e)
+		if err == nil {
+			continue
+		}
+		if !e.After {
+			return fmt.Errorf("maker: Node.%s: listener before '%s': %w", e.Op, e.Namespace, err)
+		}
+		errs = append(errs, fmt.Errorf("maker: Node.%s: listener after '%s': %w", e.Op, e.Namespace, err))
+	}
+	return errors.Join(errs...)
+}
+
+// mergedValues returns values of the node with the values set.
+func (n *Node) mergedValues(values map[string]string) map[string]string {
+	out := maps.Clone(n.values)
+	maps.Copy(out, values)
+	return out
+}
@@ -0,0 +1,86 @@
+package maker
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vologzhan/maker/template"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// Options of the project, see NewWithOptions.
+type Options struct {
+	OnFlush   func(e FlushEvent) error // called after hooks of the template when Flush has written changes
+	Listeners []Listener               // called before and after operations on nodes, in order
+	render    bool                     // internal render of Upgrade, hooks, listeners and the lock are skipped
+}
+
+// FlushEvent describes changes written by Flush.
+type FlushEvent struct {
+	Path  string   // the root of the project
+	Files []string // created, changed and deleted files and directories
+	Nodes []*Node  // created, changed and deleted nodes
+}
+
+// HookError is the failure of a post-flush hook, Flush returns failures of all hooks joined.
+type HookError struct {
+	Hook string
+	Err  error
+}
+
+func (e *HookError) Error() string { return fmt.Sprintf("maker: hook '%s': %s", e.Hook, e.Err) }
+func (e *HookError) Unwrap() error { return e.Err }
+
+func runHooks(root *Node, e FlushEvent) error {
+	if root.options.render {
+		return nil
+	}
+
+	var errs []error
+
+	if cfg := root.template.Config; cfg != nil {
+		for _, hook := range cfg.Hooks.PostFlush {
+			if !hookMatches(hook, e.Files) {
+				continue
+			}
+			if err := runHook(hook, e); err != nil {
+				errs = append(errs, &HookError{hook.Name, err})
+			}
+		}
+	}
+
+	if root.options.OnFlush != nil {
+		if err := root.options.OnFlush(e); err != nil {
+			errs = append(errs, &HookError{"OnFlush", err})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func hookMatches(hook template.Hook, files []string) bool {
+	if hook.Files == "" {
+		return true
+	}
+	for _, file := range files {
+		if matched, _ := path.Match(hook.Files, path.Base(file)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func runHook(hook template.Hook, e FlushEvent) error {
+	cmd := exec.Command(hook.Run[0], hook.Run[1:]...)
+	cmd.Dir = e.Path
+	cmd.Env = append(os.Environ(), "MAKER_CHANGED_FILES="+strings.Join(e.Files, "\n"))
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w, output:\n%s", err, out)
+	}
+
+	return nil
+}
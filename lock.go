@@ -0,0 +1,89 @@
+package maker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/vologzhan/maker/source"
+	"github.com/vologzhan/maker/template"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LockFile records the template bundle the project was generated with, it is placed in the root of the project.
+const LockFile = source.LockFile
+
+type Lock struct {
+	Template string `yaml:"template"`
+	Version  string `yaml:"version"`
+}
+
+func readLock(srcPath string) (*Lock, error) {
+	content, err := os.ReadFile(filepath.Join(srcPath, LockFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lock{}
+	if err := yaml.Unmarshal(content, lock); err != nil {
+		return nil, fmt.Errorf("maker: readLock: %w", err)
+	}
+
+	return lock, nil
+}
+
+func writeLock(srcPath string, cfg *template.Config) error {
+	if cfg == nil || cfg.Name == "" {
+		return nil
+	}
+
+	content, err := yaml.Marshal(&Lock{cfg.Name, cfg.Version})
+	if err != nil {
+		return err
+	}
+
+	lockPath := filepath.Join(srcPath, LockFile)
+	if current, err := os.ReadFile(lockPath); err == nil && bytes.Equal(current, content) {
+		return nil
+	}
+
+	return os.WriteFile(lockPath, content, 0644)
+}
+
+// checkLock returns warnings if the project was generated with the incompatible template bundle.
+func checkLock(lock *Lock, cfg *template.Config) []string {
+	if lock == nil || cfg == nil || cfg.Name == "" {
+		return nil
+	}
+
+	if lock.Template != cfg.Name {
+		return []string{fmt.Sprintf("project was generated with template '%s', but read with '%s'", lock.Template, cfg.Name)}
+	}
+
+	lockVersion, tplVersion := canonicalVersion(lock.Version), canonicalVersion(cfg.Version)
+	if !semver.IsValid(lockVersion) || !semver.IsValid(tplVersion) {
+		return []string{fmt.Sprintf("unable to compare template versions '%s' and '%s'", lock.Version, cfg.Version)}
+	}
+
+	if semver.Major(lockVersion) != semver.Major(tplVersion) {
+		return []string{fmt.Sprintf("project was generated with template '%s' %s, incompatible with %s", cfg.Name, lock.Version, cfg.Version)}
+	}
+	if semver.Compare(lockVersion, tplVersion) > 0 {
+		return []string{fmt.Sprintf("project was generated with newer template '%s' %s, than %s", cfg.Name, lock.Version, cfg.Version)}
+	}
+
+	return nil
+}
+
+func canonicalVersion(v string) string {
+	if v != "" && !strings.HasPrefix(v, "v") {
+		return "v" + v
+	}
+	return v
+}
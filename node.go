@@ -8,6 +8,7 @@ import (
 	"github.com/vologzhan/maker-common/strcase"
 	"github.com/vologzhan/maker/source"
 	"github.com/vologzhan/maker/template"
+	"maps"
 )
 
 type Node struct {
@@ -18,10 +19,18 @@ type Node struct {
 	entrypoints []source.Node
 	inserts     map[string][]*source.Insert
 	values      map[string]string
+	warnings    []string // set for the root node only
+	options     Options  // set for the root node only
+	changed     []*Node  // set for the root node only, nodes changed since the last flush
 }
 
 func New(tpl *template.Namespace, srcPath string) (*Node, error) {
+	return NewWithOptions(tpl, srcPath, Options{})
+}
+
+func NewWithOptions(tpl *template.Namespace, srcPath string, opts Options) (*Node, error) {
 	n := newNode(uuid.New(), tpl, nil, nil)
+	n.options = opts
 
 	tplEntry := tpl.Entrypoints[0].(*template.Dir)
 	srcEntry := source.New(tplEntry, srcPath)
@@ -29,6 +38,12 @@ func New(tpl *template.Namespace, srcPath string) (*Node, error) {
 	n.entrypoints = append(n.entrypoints, srcEntry)
 	n.values["path"] = srcPath
 
+	lock, err := readLock(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	n.warnings = checkLock(lock, tpl.Config)
+
 	return n, nil
 }
 
@@ -38,11 +53,23 @@ func (n *Node) Values() map[string]string      { return n.values }
 func (n *Node) ValueString(name string) string { return n.values[name] }
 func (n *Node) ValueBool(name string) bool     { return n.values[name] != "" }
 
+// Warnings returns problems found while opening the project, e.g. incompatible version of the template.
+func (n *Node) Warnings() []string {
+	return n.root().warnings
+}
+
 func (n *Node) SetValues(values map[string]string) error {
 	if err := n.readPaths(); err != nil {
 		return err
 	}
 
+	old := maps.Clone(n.values)
+	if err := n.emit(Event{OpSetValues, false, n, n.template.Name, old, n.mergedValues(values)}); err != nil {
+		return err
+	}
+
+	n.markChanged()
+
 	for name, value := range values {
 		n.values[name] = value
 
@@ -53,9 +80,11 @@ func (n *Node) SetValues(values map[string]string) error {
 		}
 	}
 
-	return nil
+	return n.emit(Event{OpSetValues, true, n, n.template.Name, old, maps.Clone(n.values)})
 }
 
+// CreateChild creates the child node. If listeners called after the creation fail,
+// the child stays created and is returned with their errors.
 func (n *Node) CreateChild(nspace string, id uuid.UUID, values map[string]string) (*Node, error) {
 	tpl, ok := n.template.Children[nspace]
 	if !ok {
@@ -66,8 +95,13 @@ func (n *Node) CreateChild(nspace string, id uuid.UUID, values map[string]string
 		return nil, err
 	}
 
+	if err := n.emit(Event{OpCreateChild, false, n, nspace, nil, maps.Clone(values)}); err != nil {
+		return nil, err
+	}
+
 	child := newNode(id, tpl, n, values)
 	n.children[tpl.Name] = append(n.children[tpl.Name], child)
+	child.markChanged()
 
 	for _, tplEntry := range tpl.Entrypoints {
 		srcParent := findSourceByTemplate(n, tplEntry.Parent())
@@ -94,7 +128,7 @@ func (n *Node) CreateChild(nspace string, id uuid.UUID, values map[string]string
 		}
 	}
 
-	return child, nil
+	return child, n.emit(Event{OpCreateChild, true, child, nspace, nil, maps.Clone(child.values)})
 }
 
 func (n *Node) Children(nspace string) ([]*Node, error) {
@@ -119,6 +153,14 @@ func (n *Node) Delete() error {
 		return err
 	}
 
+	if err := n.emit(Event{OpDelete, false, n, n.template.Name, maps.Clone(n.values), nil}); err != nil {
+		return err
+	}
+
+	if err := n.deleteReferencing(); err != nil {
+		return err
+	}
+
 	for _, entry := range n.entrypoints {
 		if err := source.DeleteNode(entry); err != nil {
 			return err
@@ -126,20 +168,64 @@ func (n *Node) Delete() error {
 	}
 
 	n.parent.children[n.template.Name] = slices.Delete(n.parent.children[n.template.Name], n)
+	n.markChanged()
 
-	return nil
+	return n.emit(Event{OpDelete, true, n, n.template.Name, maps.Clone(n.values), nil})
 }
 
-// Flush apply changes to file system.
+// Flush apply changes to file system, then runs post-flush hooks if something is changed.
 // Nodes cannot be rolled back.
 func (n *Node) Flush() error {
+	if err := n.emit(Event{OpFlush, false, n, n.template.Name, nil, nil}); err != nil {
+		return err
+	}
+
+	root := n.root()
+	var changed []*Node
+	for _, node := range root.changed {
+		if node.isDescendantOf(n) {
+			changed = append(changed, node)
+		}
+	}
+	if err := n.checkReferences(changed); err != nil {
+		return err
+	}
+
+	var files []string
 	for _, src := range n.entrypoints {
+		changed, err := source.ChangedFiles(src)
+		if err != nil {
+			return err
+		}
+		files = append(files, changed...)
+
 		if err := source.SaveRecursive(src); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	if n == root && !root.options.render {
+		if err := writeLock(n.values["path"], n.template.Config); err != nil {
+			return err
+		}
+	}
+
+	var nodes, rest []*Node
+	for _, node := range root.changed {
+		if node.isDescendantOf(n) {
+			nodes = append(nodes, node)
+		} else {
+			rest = append(rest, node)
+		}
+	}
+	root.changed = rest
+
+	err := n.emit(Event{OpFlush, true, n, n.template.Name, nil, nil})
+	if len(files) == 0 && len(nodes) == 0 {
+		return err
+	}
+
+	return errors.Join(err, runHooks(root, FlushEvent{root.values["path"], files, nodes}))
 }
 
 func (n *Node) getCurrentOrParent(nspace string) *Node {
@@ -291,6 +377,11 @@ func setInserts(node *Node, src source.Node) error {
 
 		node.inserts[ins.Template.Name] = append(node.inserts[ins.Template.Name], ins)
 
+		if node.values[ins.Template.Name] == "" {
+			if value := source.InitialValue(ins); value != "" {
+				node.values[ins.Template.Name] = value // e.g. the number of the protobuf field
+			}
+		}
 		if err := ins.SetValue(node.values[ins.Template.Name]); err != nil {
 			return err
 		}
@@ -317,9 +408,9 @@ func getInserts(node *Node, src source.Node) error {
 		}
 	}
 
-	if template.IsEntry(src.GetTemplate()) {
+	if template.IsEntry(src.GetTemplate()) && !isEntrypointOf(node, src.GetTemplate()) {
 		var ins *source.Insert
-		for _, child := range source.GetChildren(src) {
+		for _, child := range lineChildren(src) {
 			i, ok := child.(*source.Insert)
 			if !ok || !i.Template.ForMerge {
 				continue
@@ -350,7 +441,7 @@ func getInserts(node *Node, src source.Node) error {
 				return fmt.Errorf("engine: getInserts: child namespace '%s' does not exist", strcase.ToSnake(ins.Template.Namespace))
 			}
 
-			child = newNode(uuid.New(), childTpl, node, nil)
+			child = newNode(uuid.New(), childTpl, node, map[string]string{ins.Template.Name: ins.Value})
 			node.children[ins.Template.Namespace] = append(node.children[ins.Template.Namespace], child)
 		}
 
@@ -367,6 +458,15 @@ func getInserts(node *Node, src source.Node) error {
 	return nil
 }
 
+func isEntrypointOf(node *Node, tpl template.Node) bool {
+	for _, entry := range node.template.Entrypoints {
+		if entry == tpl {
+			return true
+		}
+	}
+	return false
+}
+
 func newNode(id uuid.UUID, tpl *template.Namespace, parent *Node, values map[string]string) *Node {
 	if values == nil {
 		values = make(map[string]string)
@@ -380,5 +480,47 @@ func newNode(id uuid.UUID, tpl *template.Namespace, parent *Node, values map[str
 		nil,
 		make(map[string][]*source.Insert),
 		values,
+		nil,
+		Options{},
+		nil,
+	}
+}
+
+func (n *Node) root() *Node {
+	for n.parent != nil {
+		n = n.parent
+	}
+	return n
+}
+
+func (n *Node) markChanged() {
+	root := n.root()
+	for _, node := range root.changed {
+		if node == n {
+			return
+		}
+	}
+	root.changed = append(root.changed, n)
+}
+
+func (n *Node) isDescendantOf(ancestor *Node) bool {
+	for ; n != nil; n = n.parent {
+		if n == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// lineChildren returns the children of the source node, the items of joined lines are included.
+func lineChildren(src source.Node) []source.Node {
+	var out []source.Node
+	for _, child := range source.GetChildren(src) {
+		if joined, ok := child.(*source.Joined); ok {
+			out = append(out, source.GetChildren(joined)...)
+			continue
+		}
+		out = append(out, child)
 	}
+	return out
 }
@@ -1,6 +1,8 @@
 package maker
 
 import (
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/otiai10/copy"
 	"github.com/stretchr/testify/assert"
@@ -12,6 +14,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestCreate(t *testing.T) {
@@ -78,6 +81,14 @@ func TestRead(t *testing.T) {
 		"name": "hello",
 	}, service.values)
 
+	sqls := service.mustChildren(t, "sql")
+	require.Equal(t, 1, len(sqls))
+	assert.Equal(t, map[string]string{
+		"name": "20240109_init",
+		"up":   `CREATE TABLE "user"();`,
+		"down": `DROP TABLE "user";`,
+	}, sqls[0].values)
+
 	entities := service.mustChildren(t, "entity")
 	require.Equal(t, 1, len(entities))
 
@@ -283,6 +294,480 @@ func TestUnableToDeleteRootNode(t *testing.T) {
 	assert.EqualError(t, err, "maker: Node.Delete: unable to delete root node")
 }
 
+func TestLockTemplateVersion(t *testing.T) {
+	source.Test = true
+
+	tmpDir := mustCreateTmpDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	newVersioned := func(version string) *Node {
+		tpl, err := template.New(os.DirFS("_test/_template-go"), "", fstest.MapFS{
+			template.ConfigFile: {Data: []byte("name: go-service\nversion: " + version + "\n")},
+		})
+		require.NoError(t, err)
+
+		n, err := New(tpl, tmpDir)
+		require.NoError(t, err)
+
+		return n
+	}
+
+	root := newVersioned("v1.2.0")
+	assert.Empty(t, root.Warnings())
+	root.mustCreateChild(t, "service", uuid.New(), map[string]string{"name": "hello"})
+	root.mustFlush(t)
+
+	lock, err := os.ReadFile(filepath.Join(tmpDir, LockFile))
+	require.NoError(t, err)
+	assert.Equal(t, "template: go-service\nversion: v1.2.0\n", string(lock))
+
+	source.Test = false // flushed files are read without ".e" suffix
+	report, err := newVersioned("v1.2.0").Report()
+	source.Test = true
+	require.NoError(t, err)
+	assert.Empty(t, report, "the lock file is not reported as unmatched")
+
+	assert.Empty(t, newVersioned("v1.3.0").Warnings())
+	assert.Equal(t, []string{"project was generated with template 'go-service' v1.2.0, incompatible with v2.0.0"}, newVersioned("v2.0.0").Warnings())
+}
+
+func TestProtoFieldNumbers(t *testing.T) {
+	source.Test = true
+
+	tmpDir := mustCreateTmpDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	tpl, err := template.New(os.DirFS("_test/_template-go"), "", fstest.MapFS{
+		"{!service-name}-service/proto/{entity-name}.proto.t": {Data: []byte(`syntax = "proto3";
+
+message ▶EntityName◀ {
+⏩  ▶⬇Attribute➡TypeGo◀ ▶⬇attribute_name◀ = ▶⬇Attribute➡ProtoNumber◀;⏪
+}
+`)},
+	})
+	require.NoError(t, err)
+
+	root, err := New(tpl, tmpDir)
+	require.NoError(t, err)
+	service := root.mustCreateChild(t, "service", uuid.New(), map[string]string{"name": "hello"})
+	entity := service.mustCreateChild(t, "entity", uuid.New(), map[string]string{"name": "user", "name_db": "user", "plural_name": "users"})
+	id := entity.mustCreateChild(t, "attribute", uuid.New(), map[string]string{"name": "id", "type_go": "int64", "name_db": "id", "type_db": "serial"})
+	name := entity.mustCreateChild(t, "attribute", uuid.New(), map[string]string{"name": "name", "type_go": "string", "name_db": "name", "type_db": "text"})
+	assert.Equal(t, "1", id.ValueString("proto_number"), "the number is assigned on create")
+	assert.Equal(t, "2", name.ValueString("proto_number"))
+	root.mustFlush(t)
+
+	proto, err := os.ReadFile(filepath.Join(tmpDir, "hello-service/proto/user.proto"))
+	require.NoError(t, err)
+	assert.Equal(t, "syntax = \"proto3\";\n\nmessage User {\n  int64 id = 1;\n  string name = 2;\n}\n", string(proto))
+
+	source.Test = false // flushed files are read without ".e" suffix
+	defer func() { source.Test = true }()
+
+	root, err = New(tpl, tmpDir)
+	require.NoError(t, err)
+	entity = root.mustChildren(t, "service")[0].mustChildren(t, "entity")[0]
+	attributes := entity.mustChildren(t, "attribute")
+	require.Equal(t, 2, len(attributes))
+	kept := attributes[1]
+	attributes[0].mustDelete(t)
+	created := entity.mustCreateChild(t, "attribute", uuid.New(), map[string]string{"name": "email", "type_go": "string", "name_db": "email", "type_db": "text"})
+	assert.Equal(t, "2", kept.ValueString("proto_number"), "the read number is kept")
+	assert.Equal(t, "3", created.ValueString("proto_number"))
+}
+
+func TestFlushHooks(t *testing.T) {
+	source.Test = true
+
+	tmpDir := mustCreateTmpDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	tpl, err := template.New(os.DirFS("_test/_template-go"), "", fstest.MapFS{
+		template.ConfigFile: {Data: []byte(`hooks:
+  post_flush:
+    - name: changed
+      run: [sh, -c, "echo \"$MAKER_CHANGED_FILES\" > changed.txt"]
+    - run: [sh, -c, "exit 3"]
+    - name: sql
+      run: [sh, -c, "exit 4"]
+      files: "*.sql"
+`)},
+	})
+	require.NoError(t, err)
+
+	var event FlushEvent
+	root, err := NewWithOptions(tpl, tmpDir, Options{OnFlush: func(e FlushEvent) error {
+		event = e
+		return nil
+	}})
+	require.NoError(t, err)
+
+	service := root.mustCreateChild(t, "service", uuid.New(), map[string]string{"name": "hello"})
+	err = root.Flush()
+
+	var hookErr *HookError
+	require.ErrorAs(t, err, &hookErr)
+	assert.Equal(t, "sh -c exit 3", hookErr.Hook)
+	assert.NotContains(t, err.Error(), "'sql'", "no sql files are changed")
+
+	changed, err := os.ReadFile(filepath.Join(tmpDir, "changed.txt"))
+	require.NoError(t, err)
+	assert.Contains(t, string(changed), filepath.Join(tmpDir, "hello-service/go.mod"))
+
+	assert.Equal(t, tmpDir, event.Path)
+	assert.Equal(t, []*Node{service}, event.Nodes)
+	assert.Contains(t, event.Files, filepath.Join(tmpDir, "hello-service/go.mod"))
+
+	event = FlushEvent{}
+	require.NoError(t, root.Flush(), "hooks don't run without changes")
+	assert.Empty(t, event.Files)
+}
+
+func TestReadFileEntry(t *testing.T) {
+	source.Test = false
+	defer func() { source.Test = true }()
+
+	tmpDir := mustCreateTmpDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "hello/notes"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "hello/notes/first.txt"), []byte("title: First\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "hello/notes/first.meta"), []byte("author: Ann\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "hello/notes/second.txt"), []byte("title: Second\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "hello/notes/second.meta"), []byte("author: Bob\n"), 0644))
+
+	tpl, err := template.New(fstest.MapFS{
+		"{!service-name}/notes/{!note-name}.txt.t": {Data: []byte("title: ▶⬇Note➡Title◀\n")},
+		"{!service-name}/notes/{note-name}.meta.t": {Data: []byte("author: ▶⬇Note➡Author◀\n")},
+	}, "")
+	require.NoError(t, err)
+
+	root, err := New(tpl, tmpDir)
+	require.NoError(t, err)
+
+	service := root.mustChildren(t, "service")[0]
+	assert.Equal(t, map[string]string{"name": "hello"}, service.values, "the content of the file entry is not read into the parent")
+
+	notes := service.mustChildren(t, "note")
+	require.Equal(t, 2, len(notes))
+	for _, note := range notes {
+		require.NoError(t, note.readPaths())
+	}
+	assert.Equal(t, map[string]string{"name": "first", "title": "First", "author": "Ann"}, notes[0].values)
+	assert.Equal(t, map[string]string{"name": "second", "title": "Second", "author": "Bob"}, notes[1].values)
+}
+
+func TestListeners(t *testing.T) {
+	tmpDir := mustCopyToTmp(t, "_test/_short-service")
+	defer os.RemoveAll(tmpDir)
+
+	source.Test = true
+	tpl, err := template.New(os.DirFS("_test/_template-go"), "")
+	require.NoError(t, err)
+
+	var audit []string
+	root, err := NewWithOptions(tpl, tmpDir, Options{Listeners: []Listener{
+		func(e Event) error {
+			if !e.After && e.Namespace == "attribute" && strings.HasPrefix(e.New["name"], "price") && e.New["type_go"] == "float64" {
+				return errors.New("money must not be float64")
+			}
+			return nil
+		},
+		func(e Event) error {
+			if e.After {
+				audit = append(audit, fmt.Sprintf("%s %s %s->%s", e.Op, e.Namespace, e.Old["type_go"], e.New["type_go"]))
+			}
+			return nil
+		},
+	}})
+	require.NoError(t, err)
+
+	entity := root.mustChildren(t, "service")[0].mustChildren(t, "entity")[0]
+
+	_, err = entity.CreateChild("attribute", uuid.New(), map[string]string{"name": "price", "type_go": "float64"})
+	assert.ErrorContains(t, err, "maker: Node.CreateChild: listener before 'attribute': money must not be float64")
+	assert.Empty(t, audit, "the rejected operation is not applied")
+
+	price := entity.mustCreateChild(t, "attribute", uuid.New(), map[string]string{"name": "price", "type_go": "int64"})
+	assert.Error(t, price.SetValues(map[string]string{"type_go": "float64"}))
+	require.NoError(t, price.SetValues(map[string]string{"type_go": "decimal.Decimal"}))
+	price.mustDelete(t)
+
+	assert.Equal(t, []string{
+		"CreateChild attribute ->int64",
+		"SetValues attribute int64->decimal.Decimal",
+		"Delete attribute decimal.Decimal->",
+	}, audit)
+}
+
+func TestListenersAfterError(t *testing.T) {
+	tmpDir := mustCopyToTmp(t, "_test/_short-service")
+	defer os.RemoveAll(tmpDir)
+
+	source.Test = true
+	tpl, err := template.New(os.DirFS("_test/_template-go"), "")
+	require.NoError(t, err)
+
+	var calls int
+	root, err := NewWithOptions(tpl, tmpDir, Options{Listeners: []Listener{
+		func(e Event) error {
+			if e.After && e.Op == OpCreateChild {
+				return errors.New("audit is unavailable")
+			}
+			return nil
+		},
+		func(e Event) error {
+			if e.After && e.Op == OpCreateChild {
+				calls++
+			}
+			return nil
+		},
+	}})
+	require.NoError(t, err)
+
+	entity := root.mustChildren(t, "service")[0].mustChildren(t, "entity")[0]
+	count := len(entity.mustChildren(t, "attribute"))
+
+	price, err := entity.CreateChild("attribute", uuid.New(), map[string]string{"name": "price", "type_go": "int64"})
+	assert.EqualError(t, err, "maker: Node.CreateChild: listener after 'attribute': audit is unavailable")
+	require.NotNil(t, price, "the created node is returned with the error")
+	assert.Equal(t, 1, calls, "the next listeners are called")
+	assert.Len(t, entity.mustChildren(t, "attribute"), count+1)
+	assert.Equal(t, "price", price.ValueString("name"))
+
+	root.mustFlush(t)
+}
+
+func TestReferences(t *testing.T) {
+	newReferenced := func(t *testing.T, srcDir, to, onDelete string) *Node {
+		source.Test = true
+		tpl, err := template.New(os.DirFS("_test/_template-go"), "", fstest.MapFS{
+			template.ConfigFile: {Data: []byte("references:\n  - from: attribute.fk_table\n    to: " + to + "\n    on_delete: " + onDelete + "\n")},
+		})
+		require.NoError(t, err)
+
+		root, err := New(tpl, srcDir)
+		require.NoError(t, err)
+
+		return root
+	}
+	findEntity := func(t *testing.T, root *Node, name string) *Node {
+		for _, entity := range root.mustChildren(t, "service")[0].mustChildren(t, "entity") {
+			if entity.ValueString("name") == name {
+				return entity
+			}
+		}
+		require.FailNow(t, "entity not found", name)
+		return nil
+	}
+
+	t.Run("restrict", func(t *testing.T) {
+		tmpDir := mustCopyToTmp(t, "_test/_short-service")
+		defer os.RemoveAll(tmpDir)
+
+		root := newReferenced(t, tmpDir, "entity.name_db", "restrict")
+		user := findEntity(t, root, "user")
+		assert.EqualError(t, user.Delete(), "maker: Node.Delete: entity 'user' is referenced by attribute 'UserUuid' (attribute.fk_table)")
+
+		user.mustCreateChild(t, "attribute", uuid.New(), map[string]string{"name": "group_id", "type_go": "int", "name_db": "group_id", "type_db": "int", "fk_table": "group"})
+		assert.EqualError(t, root.Flush(), "maker: Node.Flush: attribute 'group_id' references missing entity.name_db 'group'")
+	})
+
+	t.Run("cascade", func(t *testing.T) {
+		tmpDir := mustCopyToTmp(t, "_test/_short-service")
+		defer os.RemoveAll(tmpDir)
+
+		root := newReferenced(t, tmpDir, "entity.name_db", "cascade")
+		findEntity(t, root, "user").mustDelete(t)
+		root.mustFlush(t)
+
+		profile := findEntity(t, newReferenced(t, tmpDir, "entity.name_db", "cascade"), "user-profile")
+		require.NotEmpty(t, profile.mustChildren(t, "attribute"))
+		for _, attribute := range profile.mustChildren(t, "attribute") {
+			assert.NotEqual(t, "UserUuid", attribute.ValueString("name"))
+		}
+	})
+
+	t.Run("names are compared in snake case", func(t *testing.T) {
+		tmpDir := mustCopyToTmp(t, "_test/_short-service")
+		defer os.RemoveAll(tmpDir)
+
+		root := newReferenced(t, tmpDir, "entity.name", "restrict")
+		profile := findEntity(t, root, "user-profile")
+		user := findEntity(t, root, "user")
+		user.mustCreateChild(t, "attribute", uuid.New(), map[string]string{"name": "profile_id", "type_go": "int", "name_db": "profile_id", "type_db": "int", "fk_table": "user_profile"})
+		user.mustCreateChild(t, "attribute", uuid.New(), map[string]string{"name": "ProfileUuid", "type_go": "int", "name_db": "profile_uuid", "type_db": "int", "fk_table": "UserProfile"})
+		require.NoError(t, root.Flush())
+
+		assert.ErrorContains(t, profile.Delete(), "maker: Node.Delete: entity 'user-profile' is referenced by attribute 'profile_id' (attribute.fk_table)")
+	})
+}
+
+func TestUpgrade(t *testing.T) {
+	srcDir := mustCopyToTmp(t, "_test/_full-service")
+	defer os.RemoveAll(srcDir)
+
+	source.Test = false // project is written by upgrade, so it is read without ".e" suffix
+	defer func() { source.Test = true }()
+
+	err := filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !strings.HasSuffix(path, ".e") {
+			return err
+		}
+		return os.Rename(path, strings.TrimSuffix(path, ".e"))
+	})
+	require.NoError(t, err)
+
+	modelPath := filepath.Join(srcDir, "hello-service/shared/postgres/models/user.go")
+	model, err := os.ReadFile(modelPath)
+	require.NoError(t, err)
+	model = []byte(strings.Replace(string(model), "\n\n\t// maker:keep-model-relations", "\n\t// hand-written comment\n\n\t// maker:keep-model-relations", 1))
+	model = append(model, "\nfunc (m *User) IsDeleted() bool {\n\treturn m.DeletedAt != nil\n}\n"...)
+	require.NoError(t, os.WriteFile(modelPath, model, 0644))
+
+	tplDir := os.DirFS("_test/_template-go")
+	oldTpl, err := template.New(tplDir, "")
+	require.NoError(t, err)
+
+	modelTplPath := "{!service-name}-service/{!}shared/postgres/models/{!entity-name}.go.t"
+	modelTpl, err := fs.ReadFile(tplDir, modelTplPath)
+	require.NoError(t, err)
+	newTpl, err := template.New(tplDir, "", fstest.MapFS{
+		modelTplPath: {Data: []byte(strings.Replace(string(modelTpl), "◀\"` // maker", "◀\" json:\"▶⬇Attribute➡NameDb◀\"` // maker", 1))},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, Upgrade(oldTpl, newTpl, srcDir))
+
+	model, err = os.ReadFile(modelPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(model), "\tId        int        `bun:\"id,pk\" json:\"id\"`              // maker:type_db=serial\n")
+	assert.Contains(t, string(model), "json:\"deleted_at\"` // maker:type_db=timestamp(0),default=null\n\t// hand-written comment\n")
+	assert.True(t, strings.HasSuffix(string(model), "}\n\nfunc (m *User) IsDeleted() bool {\n\treturn m.DeletedAt != nil\n}\n"))
+
+	dto, err := os.ReadFile(filepath.Join(srcDir, "hello-service/shared/dto/user.go"))
+	require.NoError(t, err)
+	expectedDto, err := os.ReadFile("_test/_full-service/hello-service/shared/dto/user.go.e")
+	require.NoError(t, err)
+	assert.Equal(t, string(expectedDto), string(dto))
+}
+
+func TestUpgradeSkipsHooks(t *testing.T) {
+	srcDir := mustCopyToTmp(t, "_test/_full-service")
+	defer os.RemoveAll(srcDir)
+
+	source.Test = false
+	defer func() { source.Test = true }()
+
+	err := filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !strings.HasSuffix(path, ".e") {
+			return err
+		}
+		return os.Rename(path, strings.TrimSuffix(path, ".e"))
+	})
+	require.NoError(t, err)
+
+	newVersioned := func(version string) *template.Namespace {
+		tpl, err := template.New(os.DirFS("_test/_template-go"), "", fstest.MapFS{
+			template.ConfigFile: {Data: []byte("name: go-service\nversion: " + version + "\nhooks:\n  post_flush:\n    - name: build\n      run: [sh, -c, \"exit 1\"]\n")},
+		})
+		require.NoError(t, err)
+		return tpl
+	}
+
+	require.NoError(t, Upgrade(newVersioned("v1.0.0"), newVersioned("v1.1.0"), srcDir), "renders of the templates don't run hooks")
+
+	lock, err := os.ReadFile(filepath.Join(srcDir, LockFile))
+	require.NoError(t, err)
+	assert.Equal(t, "template: go-service\nversion: v1.1.0\n", string(lock))
+}
+
+func TestMergeLines(t *testing.T) {
+	base := "a\nb\nc\n"
+	tests := []struct {
+		name, current, next, expected string
+		conflict                      bool
+	}{
+		{"project only", "a\nb1\nc\n", base, "a\nb1\nc\n", false},
+		{"template only", base, "a\nb\nc2\n", "a\nb\nc2\n", false},
+		{"different lines", "a1\nb\nc\n", "a\nb\nc2\n", "a1\nb\nc2\n", false},
+		{"insertion after a changed line", "a\nhand\nb\nc\n", "a2\nb\nc\n", "a2\nhand\nb\nc\n", false},
+		{"same change", "a\nb2\nc\n", "a\nb2\nc\n", "a\nb2\nc\n", false},
+		{"same line", "a\nb1\nc\n", "a\nb2\nc\n", "a\n<<<<<<< project\nb1\n=======\nb2\n>>>>>>> template\nc\n", true},
+		{"insertions at the same line", "a\nb\nc\nd1", "a\nb\nc\nd2", "a\nb\nc\n<<<<<<< project\nd1\n=======\nd2\n>>>>>>> template\n", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, conflict := mergeLines(base, test.current, test.next)
+			assert.Equal(t, test.expected, merged)
+			assert.Equal(t, test.conflict, conflict)
+		})
+	}
+}
+
+func TestUpgradeConflict(t *testing.T) {
+	srcDir := mustCopyToTmp(t, "_test/_full-service")
+	defer os.RemoveAll(srcDir)
+
+	source.Test = false
+	defer func() { source.Test = true }()
+
+	err := filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !strings.HasSuffix(path, ".e") {
+			return err
+		}
+		return os.Rename(path, strings.TrimSuffix(path, ".e"))
+	})
+	require.NoError(t, err)
+
+	modelPath := filepath.Join(srcDir, "hello-service/shared/postgres/models/user.go")
+	model, err := os.ReadFile(modelPath)
+	require.NoError(t, err)
+	model = []byte(strings.Replace(string(model), "// maker:keep-model-relations", "// maker:keep-model-relations, hand-written", 1))
+	require.NoError(t, os.WriteFile(modelPath, model, 0644))
+
+	tplDir := os.DirFS("_test/_template-go")
+	oldTpl, err := template.New(tplDir, "")
+	require.NoError(t, err)
+
+	modelTplPath := "{!service-name}-service/{!}shared/postgres/models/{!entity-name}.go.t"
+	modelTpl, err := fs.ReadFile(tplDir, modelTplPath)
+	require.NoError(t, err)
+	newTpl, err := template.New(tplDir, "", fstest.MapFS{
+		modelTplPath: {Data: []byte(strings.Replace(string(modelTpl), "// maker:keep-model-relations", "// maker:keep-relations", 1))},
+	})
+	require.NoError(t, err)
+
+	assert.EqualError(t, Upgrade(oldTpl, newTpl, srcDir), "maker: Upgrade: the project and the template changed the same lines, resolve conflicts in files: "+modelPath)
+
+	model, err = os.ReadFile(modelPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(model), "\n<<<<<<< project\n\t// maker:keep-model-relations, hand-written\n=======\n\t// maker:keep-relations\n>>>>>>> template\n}\n")
+}
+
+func TestReport(t *testing.T) {
+	srcDir := mustCopyToTmp(t, "_test/_full-service")
+	defer os.RemoveAll(srcDir)
+
+	modelPath := filepath.Join(srcDir, "hello-service/shared/postgres/models/user.go.e")
+	model, err := os.ReadFile(modelPath)
+	require.NoError(t, err)
+	model = append(model, "\n// hand-written\n"...)
+	require.NoError(t, os.WriteFile(modelPath, model, 0644))
+
+	notesPath := filepath.Join(srcDir, "hello-service/shared/dto/notes.md")
+	require.NoError(t, os.WriteFile(notesPath, []byte("notes\n"), 0644))
+
+	root := newTestMaker(t, srcDir)
+	report, err := root.Report()
+	require.NoError(t, err)
+
+	assert.Equal(t, []source.ReportItem{
+		{Kind: source.ReportUnmatchedFile, Path: notesPath},
+		{Kind: source.ReportRawLine, Path: modelPath, Line: 39, Value: "// hand-written"},
+	}, report)
+}
+
 func compareDirectory(expected, actual, relativePath string, t *testing.T) {
 	fullPath1 := filepath.Join(expected, relativePath)
 	files1, err := os.ReadDir(fullPath1)
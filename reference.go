@@ -0,0 +1,290 @@
+package maker
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vologzhan/maker-common/strcase"
+	"github.com/vologzhan/maker/template"
+	"maps"
+	"slices"
+)
+
+// scope returns the node within which the referenced nodes are searched: the node of the scope of the reference
+// or of the nearest common ancestor of both namespaces.
+func (n *Node) scope(ref template.Reference) (*Node, error) {
+	nspace, ok := n.scopeNamespace(ref)
+	if !ok {
+		return nil, fmt.Errorf("maker: Node.scope: reference '%s' -> '%s': namespaces have no common ancestor", ref.From, ref.To)
+	}
+
+	scope := n.getCurrentOrParent(nspace)
+	if scope == nil {
+		return nil, fmt.Errorf("maker: Node.scope: reference '%s' -> '%s': %s is out of scope '%s'", ref.From, ref.To, n.describe(), nspace)
+	}
+
+	return scope, nil
+}
+
+func (n *Node) scopeNamespace(ref template.Reference) (string, bool) {
+	if ref.Scope != "" {
+		return ref.Scope, true
+	}
+
+	fromNspace, _ := ref.FromNamespace()
+	toNspace, _ := ref.ToNamespace()
+
+	root := n.root().template
+	ancestors := make(map[*template.Namespace]bool)
+	for nspace := root.Find(toNspace).Parent; nspace != nil; nspace = nspace.Parent {
+		ancestors[nspace] = true
+	}
+	for nspace := root.Find(fromNspace).Parent; nspace != nil; nspace = nspace.Parent {
+		if ancestors[nspace] {
+			return nspace.Name, true // the root namespace has an empty name
+		}
+	}
+
+	return "", false
+}
+
+// descendants returns the nodes of the namespace in the subtree of the node.
+func (n *Node) descendants(nspace string) ([]*Node, error) {
+	var out []*Node
+	for _, name := range slices.Sorted(maps.Keys(n.template.Children)) {
+		if n.template.Children[name].Find(nspace) == nil {
+			continue
+		}
+
+		children, err := n.Children(name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, child := range children {
+			if name == nspace {
+				out = append(out, child)
+				continue
+			}
+
+			nodes, err := child.descendants(nspace)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, nodes...)
+		}
+	}
+	return out, nil
+}
+
+func (n *Node) value(name string) (string, error) {
+	if err := n.readPaths(); err != nil {
+		return "", err
+	}
+	return n.values[name], nil
+}
+
+// attached reports whether the node is not deleted.
+func (n *Node) attached() bool {
+	for ; n.parent != nil; n = n.parent {
+		if !slices.Contains(n.parent.children[n.template.Name], n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *Node) describe() string {
+	if name := n.values["name"]; name != "" {
+		return fmt.Sprintf("%s '%s'", n.template.Name, name)
+	}
+	return fmt.Sprintf("%s '%s'", n.template.Name, n.id)
+}
+
+// findReferenced returns the nodes the value of the node references.
+func (n *Node) findReferenced(ref template.Reference) ([]*Node, error) {
+	_, fromValue := ref.FromNamespace()
+	toNspace, toValue := ref.ToNamespace()
+
+	value, err := n.value(fromValue)
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	scope, err := n.scope(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := scope.descendants(toNspace)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*Node
+	for _, candidate := range candidates {
+		v, err := candidate.value(toValue)
+		if err != nil {
+			return nil, err
+		}
+		if strcase.ToSnake(v) == strcase.ToSnake(value) {
+			out = append(out, candidate)
+		}
+	}
+	return out, nil
+}
+
+// findReferencing returns the nodes whose values reference the node.
+func (n *Node) findReferencing(ref template.Reference) ([]*Node, error) {
+	fromNspace, fromValue := ref.FromNamespace()
+	_, toValue := ref.ToNamespace()
+
+	value, err := n.value(toValue)
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	scope, err := n.scope(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := scope.descendants(fromNspace)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*Node
+	for _, candidate := range candidates {
+		v, err := candidate.value(fromValue)
+		if err != nil {
+			return nil, err
+		}
+		if strcase.ToSnake(v) == strcase.ToSnake(value) {
+			out = append(out, candidate)
+		}
+	}
+	return out, nil
+}
+
+// checkReferences returns errors of references of the changed nodes and of the nodes referencing them.
+func (n *Node) checkReferences(changed []*Node) error {
+	cfg := n.root().template.Config
+	if cfg == nil || len(cfg.References) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, ref := range cfg.References {
+		fromNspace, fromValue := ref.FromNamespace()
+		toNspace, _ := ref.ToNamespace()
+
+		var check []*Node
+		for _, node := range changed {
+			if !node.attached() {
+				continue
+			}
+			if node.template.Name == fromNspace {
+				check = append(check, node)
+			}
+			if node.template.Name == toNspace {
+				scope, err := node.scope(ref)
+				if err != nil {
+					return err
+				}
+				nodes, err := scope.descendants(fromNspace)
+				if err != nil {
+					return err
+				}
+				check = append(check, nodes...)
+			}
+		}
+
+		seen := make(map[*Node]bool)
+		for _, node := range check {
+			if seen[node] {
+				continue
+			}
+			seen[node] = true
+
+			referenced, err := node.findReferenced(ref)
+			if err != nil {
+				return err
+			}
+			value, err := node.value(fromValue)
+			if err != nil {
+				return err
+			}
+			if value != "" && len(referenced) == 0 {
+				errs = append(errs, fmt.Errorf("maker: Node.Flush: %s references missing %s '%s'", node.describe(), ref.To, value))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// deleteReferencing applies references to the nodes referencing the node or its descendants before it is deleted:
+// refuses if a reference restricts deleting, otherwise deletes the referencing nodes.
+func (n *Node) deleteReferencing() error {
+	cfg := n.root().template.Config
+	if cfg == nil || len(cfg.References) == 0 {
+		return nil
+	}
+
+	var cascade []*Node
+	var errs []error
+	for _, ref := range cfg.References {
+		toNspace, _ := ref.ToNamespace()
+
+		targets := []*Node{n}
+		if n.template.Name != toNspace {
+			nodes, err := n.descendants(toNspace)
+			if err != nil {
+				return err
+			}
+			targets = nodes
+		}
+
+		for _, target := range targets {
+			referencing, err := target.findReferencing(ref)
+			if err != nil {
+				return err
+			}
+
+			for _, node := range referencing {
+				if node.isDescendantOf(n) {
+					continue // deleted with the node
+				}
+
+				referenced, err := node.findReferenced(ref)
+				if err != nil {
+					return err
+				}
+				if slices.ContainsFunc(referenced, func(other *Node) bool { return !other.isDescendantOf(n) }) {
+					continue // another node has the same value
+				}
+
+				if ref.OnDelete == template.OnDeleteCascade {
+					cascade = append(cascade, node)
+				} else {
+					errs = append(errs, fmt.Errorf("maker: Node.Delete: %s is referenced by %s (%s)", target.describe(), node.describe(), ref.From))
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, node := range cascade {
+		if !node.attached() {
+			continue
+		}
+		if err := node.Delete(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
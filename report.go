@@ -0,0 +1,57 @@
+package maker
+
+import (
+	"github.com/vologzhan/maker/source"
+	"sort"
+)
+
+// Report reads the whole project and returns content that matched no template,
+// it shows drift between the project and the template and hand edits that block regeneration.
+func (n *Node) Report() ([]source.ReportItem, error) {
+	if err := readAllRecursive(n); err != nil {
+		return nil, err
+	}
+
+	var out []source.ReportItem
+	for _, entry := range n.entrypoints {
+		fsEntry, ok := entry.(source.Fs)
+		if !ok {
+			continue
+		}
+
+		items, err := source.Report(fsEntry)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, items...)
+	}
+
+	return out, nil
+}
+
+func readAllRecursive(n *Node) error {
+	if err := n.readPaths(); err != nil {
+		return err
+	}
+
+	var namespaces []string
+	for nspace := range n.template.Children {
+		namespaces = append(namespaces, nspace)
+	}
+	sort.Strings(namespaces)
+
+	for _, nspace := range namespaces {
+		children, err := n.Children(nspace)
+		if err != nil {
+			return err
+		}
+
+		for _, child := range children {
+			if err := readAllRecursive(child); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
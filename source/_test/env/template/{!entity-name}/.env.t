@@ -0,0 +1,4 @@
+POSTGRES_HOST=localhost
+POSTGRES_USER=▶ENTITY_NAME◀
+⏩FEATURE_▶⬇ATTRIBUTE_NAME◀=▶⬇Attribute➡TypeGo◀⏪
+⏩LIMIT_▶⬇ATTRIBUTE_NAME◀=100⏪
@@ -0,0 +1,6 @@
+{
+  "name": "▶entity-name◀",
+  "scripts": {
+⏩    "▶⬇attribute-name◀": "▶⬇Attribute➡TypeGo◀",⏪
+  }
+}
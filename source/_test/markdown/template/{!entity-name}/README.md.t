@@ -0,0 +1,7 @@
+# ▶entity-name◀
+
+## Attributes
+
+| Name | Type |
+|------|------|
+⏩| ▶⬇attribute-name◀ | ▶⬇Attribute➡TypeGo◀ |⏪
@@ -0,0 +1,5 @@
+syntax = "proto3";
+
+message ▶EntityName◀ {
+⏩  ▶⬇attribute-type-proto◀ ▶⬇attribute-name◀ = ▶⬇Attribute➡ProtoNumber◀;⏪
+}
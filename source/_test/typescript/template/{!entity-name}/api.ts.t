@@ -0,0 +1,5 @@
+import { Client } from './client';
+⏩import { ▶⬇AttributeName◀ } from './▶⬇attribute-name◀';⏪
+
+export const client = new Client();
+⏩export const ▶⬇attributeName◀Path = '/▶⬇attribute-name◀';⏪
@@ -0,0 +1,5 @@
+services:
+⏩  ▶⬇attribute-name◀:
+    image: ▶⬇Attribute➡TypeGo◀⏪
+volumes:
+  data:
@@ -0,0 +1,207 @@
+package source
+
+import (
+	"fmt"
+	"github.com/vologzhan/maker/template"
+	"github.com/vologzhan/maker/template/lexer"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+type explainStep struct {
+	tplNodes []template.Node
+	nodes    []Stringer
+	matched  bool
+	note     string
+}
+
+// explanation collects steps of matching per source line number, every call of Explain has its own.
+type explanation map[int][]explainStep
+
+// Explain parses the content with the template file and writes every source line with the template lines
+// it was matched against, the generated regexp, the captured insert values or the reason it fell through.
+func Explain(w io.Writer, tpl *template.File, content []byte) error {
+	markers := lexer.DefaultContentMarkers
+	if tpl.Markers != nil {
+		markers = *tpl.Markers
+	}
+
+	exp := make(explanation)
+	file := &File{tpl, nil, nil, nil, "", FsStatusNotRead}
+	if err := parseContent(content, file, exp); err != nil {
+		return err
+	}
+
+	for line := splitFileToLines(content, tpl); line != nil; line = line.next {
+		if line.next == nil && line.value == "" {
+			break // the end of the last line
+		}
+
+		if _, err := fmt.Fprintf(w, "%d: %s\n", line.number, line.value); err != nil {
+			return err
+		}
+
+		steps := exp[line.number]
+		if len(steps) == 0 {
+			steps = []explainStep{{note: "consumed as part of the previous line"}}
+		}
+
+		for _, step := range steps {
+			if _, err := io.WriteString(w, step.format(markers)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// FindTemplateFile returns the template of the file by its path relative to the root of the project.
+func FindTemplateFile(root *template.Dir, relPath string) (*template.File, error) {
+	var items []template.Node = root.Items
+	segments := strings.Split(filepath.ToSlash(filepath.Clean(relPath)), "/")
+
+	for i, segment := range segments {
+		var found template.Node
+		for _, item := range items {
+			if _, isDir := item.(*template.Dir); isDir == (i == len(segments)-1) {
+				continue
+			}
+
+			fsItem, ok := item.(template.Fs)
+			if !ok {
+				continue
+			}
+
+			_, matched, err := parse(segment, fsItem.NameNodes(), nil)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				found = item
+				break
+			}
+		}
+
+		switch found := found.(type) {
+		case *template.Dir:
+			items = found.Items
+		case *template.File:
+			return found, nil
+		default:
+			return nil, fmt.Errorf("source: FindTemplateFile: no template for '%s'", relPath)
+		}
+	}
+
+	return nil, fmt.Errorf("source: FindTemplateFile: no template for '%s'", relPath)
+}
+
+// attach makes the lines collect their steps to the explanation, it does nothing for a nil explanation.
+func (e explanation) attach(first *sourceLine) {
+	if e == nil {
+		return
+	}
+	for line := first; line != nil; line = line.next {
+		line.explain = e
+	}
+}
+
+func explainAttempt(srcLine *sourceLine, tplNodes []template.Node, nodes []Stringer, matched bool) {
+	if srcLine.explain == nil {
+		return
+	}
+	srcLine.explain[srcLine.number] = append(srcLine.explain[srcLine.number], explainStep{tplNodes, nodes, matched, ""})
+}
+
+func explainLine(srcLine *sourceLine, note string) {
+	if srcLine.explain == nil {
+		return
+	}
+	srcLine.explain[srcLine.number] = append(srcLine.explain[srcLine.number], explainStep{note: note})
+}
+
+// format renders the step, the template line is written with the markers of the template.
+func (s explainStep) format(m lexer.Markers) string {
+	if s.tplNodes == nil {
+		return fmt.Sprintf("\t%s\n", s.note)
+	}
+
+	var buf strings.Builder
+	buf.WriteString("\ttemplate: " + describeTemplate(s.tplNodes, m) + "\n")
+
+	reg, err := compileRegexp(s.tplNodes)
+	if err != nil {
+		buf.WriteString("\tregexp: " + err.Error() + "\n")
+	} else {
+		buf.WriteString("\tregexp: " + reg.String() + "\n")
+	}
+
+	if !s.matched {
+		buf.WriteString("\tno match\n")
+		return buf.String()
+	}
+
+	captures := describeCaptures(s.nodes)
+	if len(captures) == 0 {
+		buf.WriteString("\tmatched\n")
+	} else {
+		buf.WriteString("\tmatched: " + strings.Join(captures, ", ") + "\n")
+	}
+
+	return buf.String()
+}
+
+func describeTemplate(nodes []template.Node, m lexer.Markers) string {
+	var buf strings.Builder
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *template.Word:
+			buf.WriteString(node.Value)
+		case *template.Separator:
+			buf.WriteString(" ")
+		case *template.Insert:
+			buf.WriteString(m.TemplateStart)
+			if node.IsKey {
+				buf.WriteString(m.TemplateKey)
+			}
+			if node.Namespace != "" {
+				buf.WriteString(node.Namespace + m.TemplateSeparator)
+			}
+			buf.WriteString(node.Name)
+			if len(node.Items) > 0 {
+				buf.WriteString(m.TemplateCondition + describeTemplate(node.Items, m))
+			}
+			buf.WriteString(m.TemplateEnd)
+		case *template.Template:
+			if node.Block {
+				buf.WriteString(m.TemplateBlockStart + describeTemplate(node.Items, m) + m.TemplateBlockEnd)
+			} else {
+				buf.WriteString(m.TemplateChildStart + describeTemplate(node.Items, m) + m.TemplateChildEnd)
+			}
+		default:
+			buf.WriteString(fmt.Sprintf("<%T>", node))
+		}
+	}
+	return buf.String()
+}
+
+func describeCaptures(nodes []Stringer) []string {
+	var out []string
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *Insert:
+			name := node.Template.Name
+			if node.Template.Namespace != "" {
+				name = node.Template.Namespace + "." + name
+			}
+			out = append(out, fmt.Sprintf("%s=%q", name, node.Value))
+			out = append(out, describeCaptures(node.Items)...)
+		case *Template:
+			out = append(out, describeCaptures(node.Items)...)
+		case *Joined:
+			out = append(out, describeCaptures(node.Items)...)
+		}
+	}
+	return out
+}
@@ -33,31 +33,17 @@ func addEntryToParent(child, parent Node) error {
 	case *Imports:
 		parent.Items = append(parent.Items, child.(*Import))
 	case *File:
-		tpl := child.GetTemplate()
-
-		var tplPrev template.Node
-		for _, n := range parent.Template.Content {
-			if n == tpl {
-				break
-			}
-			if _, ok := n.(*template.LineFeed); !ok {
-				tplPrev = n
-			}
+		content, err := insertEntry(parent.Content, parent.Template.Content, child.(Stringer))
+		if err != nil {
+			return err
 		}
-
-		for i := len(parent.Content) - 1; i >= 0; i-- {
-			currentTpl := parent.Content[i].GetTemplate()
-			if currentTpl == tpl {
-				parent.Content = slices.Insert(parent.Content, i+1, child.(Stringer))
-				return nil
-			}
-			if currentTpl == tplPrev {
-				parent.Content = slices.Insert(parent.Content, i+2, child.(Stringer)) // вставка после перевода строки
-				return nil
-			}
+		parent.Content = content
+	case *Template:
+		items, err := insertEntry(parent.Items, parent.Template.Items, child.(Stringer))
+		if err != nil {
+			return err
 		}
-
-		return fmt.Errorf("source: addEntryToParent: in file no found place to insert node")
+		parent.Items = items
 	default:
 		return fmt.Errorf("source: addEntryToParent: unexpected parent node type '%T'", parent)
 	}
@@ -65,6 +51,32 @@ func addEntryToParent(child, parent Node) error {
 	return nil
 }
 
+func insertEntry(content []Stringer, tplContent []template.Node, child Stringer) ([]Stringer, error) {
+	tpl := child.GetTemplate()
+
+	var tplPrev template.Node
+	for _, n := range tplContent {
+		if n == tpl {
+			break
+		}
+		if _, ok := n.(*template.LineFeed); !ok {
+			tplPrev = n
+		}
+	}
+
+	for i := len(content) - 1; i >= 0; i-- {
+		currentTpl := content[i].GetTemplate()
+		if currentTpl == tpl {
+			return slices.Insert(content, i+1, child), nil
+		}
+		if currentTpl == tplPrev {
+			return slices.Insert(content, i+2, child), nil // вставка после перевода строки
+		}
+	}
+
+	return nil, fmt.Errorf("source: addEntryToParent: in file no found place to insert node")
+}
+
 func createNodeRecursive(tpl template.Node, parent Node, skipEntry bool) Node {
 	switch tpl := tpl.(type) {
 	case *template.Dir:
@@ -122,7 +134,9 @@ func createNodeRecursive(tpl template.Node, parent Node, skipEntry bool) Node {
 		}
 		src := &Template{tpl, parent, nil}
 		for _, tpl := range tpl.Items {
-			src.Items = append(src.Items, createNodeRecursive(tpl, src, true).(Stringer))
+			if newNode := createNodeRecursive(tpl, src, true); newNode != nil {
+				src.Items = append(src.Items, newNode.(Stringer))
+			}
 		}
 		return src
 	case *template.Insert:
@@ -134,7 +148,7 @@ func createNodeRecursive(tpl template.Node, parent Node, skipEntry bool) Node {
 		}
 		return src
 	case *template.Imports:
-		src := &Imports{Template: tpl, Parent: parent.(*File), Items: nil}
+		src := &Imports{Template: tpl, Parent: parent.(*File), Items: nil, Tail: nil, style: newImportsStyle(parent.(*File).Template.Type)}
 		for _, tpl := range tpl.Items {
 			srcItem := createNodeRecursive(tpl, src, true)
 			if srcItem == nil {
@@ -147,7 +161,7 @@ func createNodeRecursive(tpl template.Node, parent Node, skipEntry bool) Node {
 		if tpl.Entry && skipEntry {
 			return nil
 		}
-		src := &Import{tpl, parent.(*Imports), nil, nil}
+		src := &Import{tpl, parent.(*Imports), nil, nil, nil, "", 0}
 		for _, tpl := range tpl.Name {
 			src.Name = append(src.Name, createNodeRecursive(tpl, src, true).(Stringer))
 		}
@@ -1,6 +1,7 @@
 package source
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	slicesCommon "github.com/vologzhan/maker-common/slices"
@@ -8,8 +9,10 @@ import (
 	"go/format"
 	"golang.org/x/tools/imports"
 	"os"
+	"os/exec"
 	"path"
 	"slices"
+	"strings"
 )
 
 type FsStatus int
@@ -46,8 +49,13 @@ func (n *Dir) GetParentDir() *Dir {
 	}
 	return n.Parent
 }
-func (n *File) GetParentDir() *Dir     { return n.Parent }
-func (n *File) GetParentFs() Fs        { return n.Parent }
+func (n *File) GetParentDir() *Dir { return n.Parent }
+func (n *File) GetParentFs() Fs {
+	if n.Parent == nil {
+		return nil
+	}
+	return n.Parent
+}
 func (n *Dir) GetFsStatus() FsStatus   { return n.Status }
 func (n *File) GetFsStatus() FsStatus  { return n.Status }
 func (n *Dir) SetFsStatus(s FsStatus)  { n.Status = s }
@@ -68,6 +76,49 @@ func SaveRecursive(node Node) error {
 	return saveRecursive(fs)
 }
 
+// ChangedFiles returns paths of files and directories which SaveRecursive creates, changes or deletes.
+func ChangedFiles(node Node) ([]string, error) {
+	fs, err := upToFsNode(node)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	err = changedFiles(fs, &out)
+
+	return out, err
+}
+
+func changedFiles(node Fs, out *[]string) error {
+	switch node.GetFsStatus() {
+	case FsStatusDeleted:
+		p, err := buildRealPath(node)
+		if err != nil {
+			return err
+		}
+		*out = append(*out, p)
+		return nil
+	case FsStatusNew, FsStatusChanged:
+		p, err := buildPath(node)
+		if err != nil {
+			return err
+		}
+		if _, ok := node.(*File); ok || node.GetFsStatus() == FsStatusNew || node.GetName() != node.GetRealName() {
+			*out = append(*out, p)
+		}
+	}
+
+	if dir, ok := node.(*Dir); ok {
+		for _, item := range dir.Items {
+			if err := changedFiles(item, out); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func saveRecursive(node Fs) error {
 	switch n := node.(type) {
 	case *Dir:
@@ -202,16 +253,16 @@ func createFile(node *File) error {
 		return err
 	}
 
-	file, err := os.Create(newPath)
+	content, err := buildContent(node)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	content, err := buildContent(node)
+	file, err := os.Create(newPath)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 
 	_, err = file.Write(content)
 	if err != nil {
@@ -263,16 +314,16 @@ func updateFile(f *File) error {
 		return err
 	}
 
-	file, err := os.OpenFile(realPath, os.O_WRONLY|os.O_TRUNC, 0666)
+	content, err := buildContent(f)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	content, err := buildContent(f)
+	file, err := os.OpenFile(realPath, os.O_WRONLY|os.O_TRUNC, 0666)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 
 	_, err = file.Write(content)
 	if err != nil {
@@ -283,6 +334,30 @@ func updateFile(f *File) error {
 }
 
 func buildContent(f *File) ([]byte, error) {
+	content, err := formatContent(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Template.Format == nil {
+		return content, nil
+	}
+
+	for _, cmd := range f.Template.Format.Commands {
+		if matched, _ := path.Match(cmd.Files, f.GetName()); !matched {
+			continue
+		}
+
+		content, err = runFormatCommand(cmd, f, content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return content, nil
+}
+
+func formatContent(f *File) ([]byte, error) {
 	content := []byte(concat(f.Content))
 
 	switch f.Template.Type {
@@ -292,10 +367,41 @@ func buildContent(f *File) ([]byte, error) {
 			return nil, fmt.Errorf("source: buildContent: format source error: [%w], file: [%s], content:\n%s", err, f.GetName(), content)
 		}
 
-		//imports.LocalPrefix = "" // todo
+		formatedContent, err = imports.Process("", formatedContent, nil)
+		if err != nil || f.Template.Format == nil {
+			return formatedContent, err
+		}
 
-		return imports.Process("", formatedContent, nil)
+		return groupLocalImports(formatedContent, f.Template.Format.LocalPrefix)
+	case template.FileGoMod:
+		return MergeGoMod(content, goModRequires(f.Template))
+	case template.FileJson:
+		return fixJsonCommas(content)
 	default:
 		return content, nil
 	}
 }
+
+func runFormatCommand(cmd template.FormatCommand, f *File, content []byte) ([]byte, error) {
+	filePath, err := buildPath(f)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, len(cmd.Run)-1)
+	for i, arg := range cmd.Run[1:] {
+		args[i] = strings.ReplaceAll(arg, "{path}", filePath)
+	}
+
+	var stdout, stderr bytes.Buffer
+	c := exec.Command(cmd.Run[0], args...)
+	c.Stdin = bytes.NewReader(content)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("source: buildContent: format command '%s' error: [%w], file: [%s], stderr:\n%s", strings.Join(cmd.Run, " "), err, filePath, stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,570 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vologzhan/maker/template"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestBuildContentRoundTrip reads the content of every case in _test with the template of the case
+// and writes it back, the written content must be the same. The values read to inserts are compared
+// with the "captured" file of the case, one "name: value" line per insert. The "edit" file lists the values
+// of a child created from the first child template, one "name=value" line per insert, and the content written
+// after the child is added is compared with the "edited" file. A case without these files is only written back.
+func TestBuildContentRoundTrip(t *testing.T) {
+	cases, err := os.ReadDir("_test")
+	require.NoError(t, err)
+
+	for _, c := range cases {
+		t.Run(c.Name(), func(t *testing.T) {
+			dir := filepath.Join("_test", c.Name())
+			content, err := os.ReadFile(filepath.Join(dir, "content"))
+			require.NoError(t, err)
+
+			file := mustParseTestFile(t, os.DirFS(filepath.Join(dir, "template")), string(content))
+			out, err := buildContent(file)
+			require.NoError(t, err)
+			assert.Equal(t, string(content), string(out))
+
+			captured, err := os.ReadFile(filepath.Join(dir, "captured"))
+			if errors.Is(err, fs.ErrNotExist) {
+				return // the case is only written back
+			}
+			require.NoError(t, err)
+			assert.Equal(t, string(captured), capturedValues(file))
+
+			edit, err := os.ReadFile(filepath.Join(dir, "edit"))
+			require.NoError(t, err)
+			edited, err := os.ReadFile(filepath.Join(dir, "edited"))
+			require.NoError(t, err)
+
+			createTestEntry(t, file, string(edit))
+			out, err = buildContent(file)
+			require.NoError(t, err)
+			assert.Equal(t, string(edited), string(out))
+		})
+	}
+}
+
+// capturedValues returns the values of the inserts of the node and its children, one "name: value" line per insert.
+func capturedValues(n Node) string {
+	var buf strings.Builder
+	if ins, ok := n.(*Insert); ok {
+		buf.WriteString(insertName(ins.Template) + ": " + ins.Value + "\n")
+	}
+	for _, child := range GetChildren(n) {
+		buf.WriteString(capturedValues(child))
+	}
+	return buf.String()
+}
+
+func insertName(tpl *template.Insert) string {
+	if tpl.Namespace == "" {
+		return tpl.Name
+	}
+	return tpl.Namespace + "." + tpl.Name
+}
+
+// createTestEntry adds a child created from the first child template of the file,
+// the values of its inserts are set by the "name=value" lines of the edit.
+func createTestEntry(t *testing.T, file *File, edit string) {
+	tpl := firstEntryTemplate(file.Template)
+	require.NotNil(t, tpl)
+
+	var parent Node = file
+	if tpl.Parent() != file.Template {
+		parent = findNodeByTemplate(file, tpl.Parent())
+		require.NotNil(t, parent)
+	}
+
+	entry, err := CreateEntry(tpl, parent)
+	require.NoError(t, err)
+
+	values := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(edit), "\n") {
+		name, value, ok := strings.Cut(line, "=")
+		require.True(t, ok, line)
+		values[name] = value
+	}
+	setTestValues(t, entry, values)
+}
+
+func firstEntryTemplate(tpl template.Node) template.Node {
+	var children []template.Node
+	switch tpl := tpl.(type) {
+	case *template.File:
+		children = tpl.Content
+	case *template.Template:
+		children = tpl.Items
+	case *template.Imports:
+		for _, item := range tpl.Items {
+			children = append(children, item)
+		}
+	}
+
+	for _, child := range children {
+		if template.IsEntry(child) {
+			return child
+		}
+		if entry := firstEntryTemplate(child); entry != nil {
+			return entry
+		}
+	}
+	return nil
+}
+
+func findNodeByTemplate(n Node, tpl template.Node) Node {
+	if n.GetTemplate() == tpl {
+		return n
+	}
+	for _, child := range GetChildren(n) {
+		if found := findNodeByTemplate(child, tpl); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func setTestValues(t *testing.T, n Node, values map[string]string) {
+	if ins, ok := n.(*Insert); ok {
+		value, ok := values[insertName(ins.Template)]
+		require.True(t, ok, insertName(ins.Template))
+		ins.Value = value
+	}
+	for _, child := range GetChildren(n) {
+		setTestValues(t, child, values)
+	}
+}
+
+func TestBuildContentGoMod(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/go.mod.t": {Data: []byte(`module ▶entity-name◀
+
+go 1.25.4
+
+require (
+	github.com/spf13/viper v1.21.0
+	github.com/uptrace/bun v1.2.16
+)
+
+replace github.com/uptrace/bun => ../bun
+`)},
+	}
+	content := `module user
+
+go 1.25.4
+
+require (
+	github.com/spf13/viper v1.21.0
+	github.com/uptrace/bun v1.2.16
+	github.com/uptrace/bun v1.2.18
+	github.com/google/uuid v1.6.0
+)
+
+replace github.com/uptrace/bun => ../bun
+
+replace github.com/uptrace/bun => ../../bun
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	out, err := buildContent(file)
+	require.NoError(t, err)
+	assert.Equal(t, `module user
+
+go 1.25.4
+
+require (
+	github.com/spf13/viper v1.21.0
+	github.com/uptrace/bun v1.2.18
+	github.com/google/uuid v1.6.0
+)
+
+replace github.com/uptrace/bun => ../../bun
+`, string(out))
+
+	merged, err := MergeGoMod(out, nil)
+	require.NoError(t, err)
+	assert.Equal(t, string(out), string(merged)) // nothing to merge, the content is kept as is
+}
+
+func TestBuildContentGoModBumped(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/go.mod.t": {Data: []byte(`module ▶entity-name◀
+
+go 1.25.4
+
+require (
+	github.com/spf13/viper v1.21.0
+	github.com/uptrace/bun v1.2.16
+)
+`)},
+	}
+	content := `module user
+
+go 1.25.4
+
+require (
+	github.com/google/uuid v1.6.0
+	github.com/spf13/viper v1.20.0
+	github.com/uptrace/bun v1.2.18 // bumped by the developer
+)
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	assert.Equal(t, content, concat(file.Content))
+
+	out, err := buildContent(file)
+	require.NoError(t, err)
+	assert.Equal(t, `module user
+
+go 1.25.4
+
+require (
+	github.com/google/uuid v1.6.0
+	github.com/spf13/viper v1.21.0
+	github.com/uptrace/bun v1.2.18 // bumped by the developer
+)
+`, string(out))
+}
+
+func TestBuildContentJson(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/package.json.t": {Data: []byte(`{
+  "name": "▶entity-name◀",
+  "scripts": {
+⏩    "▶⬇attribute-name◀": "▶⬇Attribute➡TypeGo◀",⏪
+  }
+}
+`)},
+	}
+	content := `{
+  "private": true,
+  "scripts": {
+    "build": "tsc",
+    "test": "jest"
+  },
+  "name": "user"
+}
+`
+
+	file := mustParseTestFile(t, fsys, content)
+
+	var scripts []*Template
+	for _, item := range file.Content {
+		if tpl, ok := item.(*Template); ok {
+			scripts = append(scripts, tpl)
+		}
+	}
+	require.Equal(t, 2, len(scripts))
+
+	entry, err := CreateEntry(scripts[0].Template, file)
+	require.NoError(t, err)
+	for _, child := range GetChildren(entry) {
+		if ins, ok := child.(*Insert); ok {
+			ins.Value = map[string]string{"name": "lint", "type_go": "eslint"}[ins.Template.Name]
+		}
+	}
+
+	out, err := buildContent(file)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "private": true,
+  "scripts": {
+    "build": "tsc",
+    "test": "jest",
+    "lint": "eslint"
+  },
+  "name": "user"
+}
+`, string(out))
+}
+
+func TestBuildContentJsonCompact(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/package.json.t": {Data: []byte(`{
+  "name": "▶entity-name◀",
+  "scripts": {
+⏩    "▶⬇attribute-name◀": "▶⬇Attribute➡TypeGo◀",⏪
+  }
+}
+`)},
+	}
+	content := `{"private":true,"scripts":{"build":"tsc","test":"jest"},"name":"user"}
+`
+
+	file := mustParseTestFile(t, fsys, content)
+
+	var scripts []string
+	for _, item := range file.Content {
+		if tpl, ok := item.(*Template); ok {
+			scripts = append(scripts, concat(tpl.Items))
+		}
+	}
+	assert.Equal(t, []string{`    "build": "tsc",`, `    "test": "jest",`}, scripts, "commas are fixed on write")
+
+	out, err := buildContent(file)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "private": true,
+  "scripts": {
+    "build": "tsc",
+    "test": "jest"
+  },
+  "name": "user"
+}
+`, string(out))
+}
+
+func TestBuildContentTsImports(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/api.ts.t": {Data: []byte(`import { Client } from './client';
+⏩import { ▶⬇AttributeName◀ } from './▶⬇attribute-name◀';⏪
+
+export const client = new Client();
+`)},
+	}
+	content := `// generated
+import {
+  Client,
+} from "./client";
+import * as models from './models';
+
+import { User } from './user';
+import { User, UserRole } from './user';
+import type { Role } from './role';
+import type { Role as R } from './role';
+
+export const client = new Client();
+`
+
+	file := mustParseTestFile(t, fsys, content)
+
+	var imports *Imports
+	for _, item := range file.Content {
+		if imps, ok := item.(*Imports); ok {
+			imports = imps
+		}
+	}
+	require.NotNil(t, imports)
+	require.Equal(t, 6, len(imports.Items))
+	assert.Equal(t, "./client", concat(imports.Items[0].Name))
+	assert.Equal(t, []string{"// generated"}, imports.Items[0].Doc)
+
+	var entry *Import
+	for _, imp := range imports.Items {
+		if imp.Template != nil && imp.Template.Entry {
+			entry = imp
+			break
+		}
+	}
+	require.NotNil(t, entry)
+	added, err := CreateEntry(entry.Template, imports)
+	require.NoError(t, err)
+	for _, child := range GetChildren(added) {
+		if ins, ok := child.(*Insert); ok {
+			ins.Value = "order"
+		}
+	}
+
+	out, err := buildContent(file)
+	require.NoError(t, err)
+	assert.Equal(t, `// generated
+import {
+  Client,
+} from "./client";
+import * as models from './models';
+
+import { User, UserRole } from './user';
+import type { Role, Role as R } from './role';
+import { Order } from './order';
+
+export const client = new Client();
+`, string(out))
+}
+
+func TestBuildContentTsImportsQuote(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/api.ts.t": {Data: []byte(`import { Client } from './client';
+⏩import { ▶⬇AttributeName◀ } from './▶⬇attribute-name◀';⏪
+
+export const client = new Client();
+`)},
+	}
+	content := `import { Client } from "./client";
+import { User } from "./user";
+
+export const client = new Client();
+`
+
+	file := mustParseTestFile(t, fsys, content)
+
+	var imports *Imports
+	for _, item := range file.Content {
+		if imps, ok := item.(*Imports); ok {
+			imports = imps
+		}
+	}
+	require.NotNil(t, imports)
+	require.Equal(t, 2, len(imports.Items))
+
+	read := imports.Items[1]
+	require.NotNil(t, read.Template)
+	for _, child := range GetChildren(read) {
+		if ins, ok := child.(*Insert); ok {
+			ins.Value = "customer"
+		}
+	}
+
+	added, err := CreateEntry(read.Template, imports)
+	require.NoError(t, err)
+	for _, child := range GetChildren(added) {
+		if ins, ok := child.(*Insert); ok {
+			ins.Value = "order"
+		}
+	}
+
+	out, err := buildContent(file)
+	require.NoError(t, err)
+	assert.Equal(t, `import { Client } from "./client";
+import { Customer } from "./customer";
+import { Order } from "./order";
+
+export const client = new Client();
+`, string(out))
+}
+
+func TestBuildContentFormat(t *testing.T) {
+	t.Setenv("MAKER_TEST_FORMAT_COMMAND", "1")
+
+	fsys := fstest.MapFS{
+		".maker.yaml": {Data: []byte(`format:
+  local_prefix: github.com/acme
+  commands:
+    - files: "*.go"
+      run: [` + os.Args[0] + `, -test.run=TestHelperFormatCommand, --, replace, "{path}", formatted]
+`)},
+		"{!entity-name}/model.go.t": {Data: []byte(`package model
+`)},
+	}
+	content := `package model
+
+import (
+	"github.com/acme/service/db"
+	"fmt"
+	"github.com/google/uuid"
+)
+
+// service/model.go
+var _ = fmt.Sprint(db.Name, uuid.Nil)
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	file.Name = []Stringer{&Word{nil, file, "model.go"}}
+	file.Parent = &Dir{nil, nil, []Stringer{&Word{nil, nil, "service"}}, []Fs{file}, "service", FsStatusNotChanged}
+
+	out, err := buildContent(file)
+	require.NoError(t, err)
+	assert.Equal(t, `package model
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/acme/service/db"
+)
+
+// formatted
+var _ = fmt.Sprint(db.Name, uuid.Nil)
+`, string(out))
+
+	file.Template.Format.Commands[0].Run = []string{os.Args[0], "-test.run=TestHelperFormatCommand", "--", "fail"}
+	_, err = buildContent(file)
+	assert.ErrorContains(t, err, "format command '"+os.Args[0]+" -test.run=TestHelperFormatCommand -- fail' error")
+}
+
+// TestHelperFormatCommand is run by format commands of the tests as a subprocess,
+// it replaces the text in stdin or fails with the arguments after "--".
+func TestHelperFormatCommand(t *testing.T) {
+	if os.Getenv("MAKER_TEST_FORMAT_COMMAND") != "1" {
+		return
+	}
+
+	args := slices.Clone(os.Args)
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if len(args) < 2 || args[1] != "replace" || len(args) != 4 {
+		os.Exit(1)
+	}
+
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		os.Exit(1)
+	}
+	fmt.Print(strings.ReplaceAll(string(content), args[2], args[3]))
+	os.Exit(0)
+}
+
+func TestGroupLocalImports(t *testing.T) {
+	content := `package model
+
+import (
+	"fmt"
+
+	// db is local
+	"github.com/acme/service/db"
+	"github.com/google/uuid"
+	"github.com/other/lib"
+)
+`
+
+	out, err := groupLocalImports([]byte(content), "github.com/acme, github.com/other")
+	require.NoError(t, err)
+	assert.Equal(t, `package model
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+
+	// db is local
+	"github.com/acme/service/db"
+	"github.com/other/lib"
+)
+`, string(out))
+
+	out, err = groupLocalImports([]byte(content), "")
+	require.NoError(t, err)
+	assert.Equal(t, content, string(out))
+}
+
+func TestBuildPathWithoutParent(t *testing.T) {
+	file := &File{nil, nil, nil, nil, "", FsStatusNotRead}
+	file.Name = []Stringer{&Word{nil, file, "model.go"}}
+
+	assert.Nil(t, file.GetParentFs())
+
+	filePath, err := buildPath(file)
+	require.NoError(t, err)
+	assert.Equal(t, "model.go", filePath)
+}
+
+func TestReportSkipsNotExisting(t *testing.T) {
+	dir := &Dir{nil, nil, nil, nil, "service", FsStatusNotChanged}
+	dir.Items = []Fs{&File{&template.File{}, dir, nil, nil, "", FsStatusNotExist}}
+
+	report, err := Report(dir)
+	require.NoError(t, err)
+	assert.Empty(t, report)
+}
@@ -0,0 +1,287 @@
+package source
+
+import (
+	"fmt"
+	"github.com/vologzhan/maker/template"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// splitGoSrcToLines splits Go source to lines like other files, go/parser only finds struct fields,
+// composite literal elements and import specs spanning several lines, they are joined to a single line
+// in gofmt single-line form, so they are matched by one template line with the same regexps.
+// A node with a comment inside is not joined. If the source is not valid Go, it is split by lines as is.
+func splitGoSrcToLines(content []byte) *sourceLine {
+	first := splitSrcToLines(content)
+
+	spans := goNodeSpans(content)
+	if len(spans) == 0 {
+		return first
+	}
+
+	for line := first; line != nil; line = line.next {
+		end, ok := spans[line.number]
+		if !ok {
+			continue
+		}
+
+		raw := []string{line.value}
+		values := []string{strings.TrimRight(line.value, " \t")}
+		for line.next != nil && line.next.number <= end {
+			raw = append(raw, line.next.value)
+			values = append(values, strings.TrimSpace(line.next.value))
+			line.next = line.next.next
+		}
+
+		line.raw = strings.Join(raw, "\n")
+		line.value = joinGoLines(values)
+	}
+
+	return first
+}
+
+// goNodeSpans returns the last line of every multi-line AST node by its first line.
+func goNodeSpans(content []byte) map[int]int {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	commentLines := make(map[int]bool)
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			commentLines[fset.Position(comment.Pos()).Line] = true
+		}
+	}
+
+	out := make(map[int]int)
+	addSpan := func(node ast.Node) {
+		start, end := fset.Position(node.Pos()).Line, fset.Position(node.End()).Line
+		if start == end || hasNestedGoBlock(fset, node) {
+			return
+		}
+		for line := start; line < end; line++ {
+			if commentLines[line] {
+				return // the comment would swallow the joined lines
+			}
+		}
+		if _, ok := out[start]; ok {
+			return
+		}
+		out[start] = end
+	}
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.StructType:
+			for _, field := range node.Fields.List {
+				addSpan(field)
+			}
+		case *ast.CompositeLit:
+			for _, elt := range node.Elts {
+				addSpan(elt)
+			}
+		case *ast.ImportSpec:
+			addSpan(node)
+		}
+		return true
+	})
+
+	return out
+}
+
+// hasNestedGoBlock reports whether the node contains a struct, interface, function or composite literal,
+// they are matched by several template lines, or a multi-line string, which must stay as is.
+func hasNestedGoBlock(fset *token.FileSet, node ast.Node) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if found || n == node {
+			return !found
+		}
+		switch n := n.(type) {
+		case *ast.StructType, *ast.InterfaceType, *ast.FuncLit, *ast.CompositeLit:
+			found = true
+		case *ast.BasicLit:
+			found = fset.Position(n.Pos()).Line != fset.Position(n.End()).Line
+		}
+		return !found
+	})
+	return found
+}
+
+// joinGoLines joins lines with a space, but not after an opening and before a closing bracket,
+// the trailing comma before a closing bracket is dropped as gofmt does for single-line lists.
+func joinGoLines(lines []string) string {
+	var buf strings.Builder
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		if i > 0 && buf.Len() > 0 {
+			prev := buf.String()
+			switch {
+			case strings.ContainsAny(line[:1], ")]}"):
+				if strings.HasSuffix(prev, ",") {
+					trimmed := strings.TrimSuffix(prev, ",")
+					buf.Reset()
+					buf.WriteString(trimmed)
+				}
+			case strings.ContainsAny(prev[len(prev)-1:], "([{"):
+			default:
+				buf.WriteString(" ")
+			}
+		}
+		buf.WriteString(line)
+	}
+	return buf.String()
+}
+
+// groupLocalImports moves imports starting with one of the comma-separated local prefixes with their comments
+// to the last group of every import declaration, as goimports -local does, but without its global state.
+func groupLocalImports(content []byte, localPrefix string) ([]byte, error) {
+	var prefixes []string
+	for _, prefix := range strings.Split(localPrefix, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return content, nil
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	isLocal := func(spec *ast.ImportSpec) bool {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		for _, prefix := range prefixes {
+			if importPath == strings.TrimSuffix(prefix, "/") || strings.HasPrefix(importPath, strings.TrimSuffix(prefix, "/")+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	lines := strings.Split(string(content), "\n")
+	for i := len(file.Decls) - 1; i >= 0; i-- {
+		decl, ok := file.Decls[i].(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT || !decl.Lparen.IsValid() {
+			continue
+		}
+
+		first, last := fset.Position(decl.Lparen).Line, fset.Position(decl.Rparen).Line-2 // 0-based lines inside the parens
+		moved := make(map[int]bool)
+		for _, spec := range decl.Specs {
+			spec := spec.(*ast.ImportSpec)
+			if !isLocal(spec) {
+				continue
+			}
+			start := spec.Pos()
+			if spec.Doc != nil {
+				start = spec.Doc.Pos()
+			}
+			for line := fset.Position(start).Line - 1; line < fset.Position(spec.End()).Line; line++ {
+				moved[line] = true
+			}
+		}
+		if len(moved) == 0 {
+			continue
+		}
+
+		var kept, local []string
+		for line := first; line <= last; line++ {
+			switch {
+			case moved[line]:
+				local = append(local, lines[line])
+			case strings.TrimSpace(lines[line]) == "" && (len(kept) == 0 || kept[len(kept)-1] == ""):
+			default:
+				kept = append(kept, lines[line])
+			}
+		}
+		if len(kept) > 0 && kept[len(kept)-1] != "" {
+			kept = append(kept, "")
+		}
+
+		lines = slices.Concat(lines[:first], kept, local, lines[last+1:])
+	}
+
+	return format.Source([]byte(strings.Join(lines, "\n")))
+}
+
+// goImports writes the import declaration of the Go file, imports are grouped like in the source.
+type goImports struct{}
+
+func (goImports) imports(n *Imports) string {
+	items, groups := n.grouped(isStdImport)
+	if len(items) == 0 {
+		return ""
+	}
+	if len(items) == 1 && len(items[0].Doc) == 0 && len(n.Tail) == 0 {
+		return fmt.Sprintf("import %s\n", items[0].String())
+	}
+
+	buf := strings.Builder{}
+	for i, item := range items {
+		if i > 0 && groups[i] != groups[i-1] {
+			buf.WriteString("\n")
+		}
+		for _, doc := range item.Doc {
+			buf.WriteString(fmt.Sprintf("\t%s\n", doc))
+		}
+		buf.WriteString(fmt.Sprintf("\t%s\n", item.String()))
+	}
+	for _, line := range n.Tail {
+		buf.WriteString(fmt.Sprintf("\t%s\n", line))
+	}
+
+	return fmt.Sprintf("import (\n%s)\n", buf.String())
+}
+
+func (goImports) importSpec(n *Import) string {
+	var out string
+	if len(n.Alias) > 0 {
+		out = fmt.Sprintf("%s \"%s\"", concat(n.Alias), concat(n.Name))
+	} else {
+		out = fmt.Sprintf("\"%s\"", concat(n.Name))
+	}
+	if n.Comment != "" {
+		out += " " + n.Comment
+	}
+	return out
+}
+
+// readGoImports reads the import declaration starting from the line, a single import or imports in parentheses,
+// returns the last line of the declaration or nil if the file has no imports.
+func readGoImports(first *sourceLine, tpl *template.Imports, f *File) (*sourceLine, error) {
+	if !strings.HasPrefix(first.value, "import") {
+		return nil, parseImports(nil, tpl, f)
+	}
+
+	explainLine(first, "imports")
+	if !strings.HasSuffix(first.value, "(") {
+		imp := strings.TrimSpace(strings.TrimPrefix(first.value, "import"))
+		return first, parseImports([]string{imp}, tpl, f)
+	}
+
+	var buf []string
+	line := first
+	for line.next != nil {
+		line = line.next
+		explainLine(line, "imports")
+		if strings.TrimSpace(line.value) == ")" {
+			return line, parseImports(buf, tpl, f)
+		}
+		buf = append(buf, line.raw)
+	}
+
+	return line, parseImports(buf, tpl, f)
+}
@@ -0,0 +1,128 @@
+package source
+
+import (
+	"fmt"
+	"github.com/vologzhan/maker/template"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"strings"
+)
+
+// MergeGoMod merges requires and replaces declared several times, e.g. by the template and by the developer:
+// the highest version of the required module is kept, so versions bumped by developers are not overwritten,
+// and the last replace of the module wins. The requires of the template are merged by the module path:
+// the version is raised to the template one if it is higher. The content is returned as is if there is nothing to merge.
+func MergeGoMod(content []byte, tplRequires []module.Version) ([]byte, error) {
+	f, err := modfile.Parse("go.mod", content, nil)
+	if err != nil {
+		return nil, fmt.Errorf("source: MergeGoMod: %w", err)
+	}
+
+	changed := false
+
+	requires := make(map[string]string)
+	var duplicatedRequires []string
+	for _, r := range f.Require {
+		version, ok := requires[r.Mod.Path]
+		if ok {
+			duplicatedRequires = append(duplicatedRequires, r.Mod.Path)
+		}
+		if !ok || semver.Compare(r.Mod.Version, version) > 0 {
+			requires[r.Mod.Path] = r.Mod.Version
+		}
+	}
+	for _, r := range tplRequires {
+		version, ok := requires[r.Path]
+		if ok && semver.Compare(r.Version, version) > 0 {
+			requires[r.Path] = r.Version
+			duplicatedRequires = append(duplicatedRequires, r.Path)
+		}
+	}
+	for _, path := range duplicatedRequires {
+		if err := f.AddRequire(path, requires[path]); err != nil {
+			return nil, fmt.Errorf("source: MergeGoMod: %w", err)
+		}
+		changed = true
+	}
+
+	replaces := make(map[module.Version]module.Version)
+	var duplicatedReplaces []module.Version
+	for _, r := range f.Replace {
+		if _, ok := replaces[r.Old]; ok {
+			duplicatedReplaces = append(duplicatedReplaces, r.Old)
+		}
+		replaces[r.Old] = r.New
+	}
+	for _, old := range duplicatedReplaces {
+		replacement := replaces[old]
+		if err := f.AddReplace(old.Path, old.Version, replacement.Path, replacement.Version); err != nil {
+			return nil, fmt.Errorf("source: MergeGoMod: %w", err)
+		}
+		changed = true
+	}
+
+	if !changed {
+		return content, nil
+	}
+
+	f.Cleanup()
+
+	return modfile.Format(f.Syntax), nil
+}
+
+// goModRequires returns the requires of the template lines without inserts.
+func goModRequires(tpl *template.File) []module.Version {
+	var lines []string
+	for line := splitTplToLines(tpl.Content); line != nil; line = line.next {
+		if line.tplEntry == nil && !hasInsert(line.nodes) {
+			lines = append(lines, tplText(line.nodes))
+		}
+	}
+
+	f, err := modfile.ParseLax("go.mod", []byte(strings.Join(lines, "\n")), nil)
+	if err != nil {
+		return nil
+	}
+
+	var out []module.Version
+	for _, r := range f.Require {
+		out = append(out, r.Mod)
+	}
+	return out
+}
+
+// matchGoModRequire matches the require of the module by its path only, so a version bumped by the developer
+// doesn't make the line unmatched. The line is kept as is, versions are merged on write.
+func matchGoModRequire(srcLine *sourceLine, tplNodes []template.Node) bool {
+	if hasInsert(tplNodes) {
+		return false
+	}
+
+	srcPath, _, ok := goModRequire(srcLine.value)
+	tplPath, _, tplOk := goModRequire(tplText(tplNodes))
+
+	return ok && tplOk && srcPath == tplPath
+}
+
+// goModRequire returns the module path and the version of the require line, in the block or not.
+func goModRequire(line string) (string, string, bool) {
+	line, _, _ = strings.Cut(line, "//")
+	fields := strings.Fields(line)
+	if len(fields) > 0 && fields[0] == "require" {
+		fields = fields[1:]
+	}
+	if len(fields) != 2 || !semver.IsValid(fields[1]) {
+		return "", "", false
+	}
+	return fields[0], fields[1], true
+}
+
+func hasInsert(nodes []template.Node) bool {
+	for _, node := range nodes {
+		if _, ok := node.(*template.Insert); ok {
+			return true
+		}
+	}
+	return false
+}
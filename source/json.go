@@ -0,0 +1,70 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// fixJsonCommas puts commas between members and elements and removes them before closing brackets,
+// so child templates can be inserted and deleted in any place. The content must be one member or element per line.
+func fixJsonCommas(content []byte) ([]byte, error) {
+	lines := strings.Split(string(content), "\n")
+
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, " \t")
+		if strings.TrimSpace(trimmed) == "" {
+			continue
+		}
+
+		next := ""
+		for j := i + 1; j < len(lines); j++ {
+			if next = strings.TrimSpace(lines[j]); next != "" {
+				break
+			}
+		}
+
+		switch {
+		case next == "" || strings.ContainsAny(next[:1], "}]"):
+			trimmed = strings.TrimSuffix(trimmed, ",")
+		case strings.ContainsAny(trimmed[len(trimmed)-1:], "{[:,"):
+		default:
+			trimmed += ","
+		}
+
+		lines[i] = trimmed
+	}
+
+	out := []byte(strings.Join(lines, "\n"))
+	if !json.Valid(out) {
+		return nil, fmt.Errorf("source: fixJsonCommas: invalid json:\n%s", out)
+	}
+
+	return out, nil
+}
+
+// indentJson formats valid JSON with one member or element per line, so compact or otherwise formatted files
+// are matched line by line too. The indentation of the first indented line is kept, two spaces by default.
+// Invalid JSON, e.g. with a comma before a closing bracket, is returned as is.
+func indentJson(content []byte) []byte {
+	trimmed := bytes.TrimRight(content, " \t\r\n")
+	if !json.Valid(trimmed) {
+		return content
+	}
+
+	indent := "  "
+	for _, line := range strings.Split(string(trimmed), "\n")[1:] {
+		if ws := line[:len(line)-len(strings.TrimLeft(line, " \t"))]; ws != "" {
+			indent = ws
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, trimmed, "", indent); err != nil {
+		return content
+	}
+
+	return append(buf.Bytes(), content[len(trimmed):]...)
+}
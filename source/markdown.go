@@ -0,0 +1,48 @@
+package source
+
+import "strings"
+
+// markdownSection is the distance between nesting levels of headings, lines of a section are nested by indentation.
+const markdownSection = 1 << 10
+
+// markdownBody is the nesting level of lines of a section, it is deeper than the deepest heading,
+// so the heading of a subsection closes the lines above it and belongs to the section.
+const markdownBody = 7 * markdownSection
+
+// markdownIndent nests Markdown lines by headings: a section contains the lines below its heading
+// and the sections of deeper headings. Lines of fenced code blocks are never headings.
+func markdownIndent() func(string) int {
+	fence := ""
+
+	return func(line string) int {
+		trimmed := strings.TrimLeft(line, " \t")
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			return markdownBody
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			return markdownBody
+		}
+		if h := headingLevel(trimmed); h > 0 {
+			return h * markdownSection
+		}
+
+		return markdownBody + indentOf(line)
+	}
+}
+
+// headingLevel returns the level of the ATX heading or 0 if the line is not a heading.
+func headingLevel(line string) int {
+	h := len(line) - len(strings.TrimLeft(line, "#"))
+	if h == 0 || h > 6 {
+		return 0
+	}
+	if h < len(line) && line[h] != ' ' && line[h] != '\t' {
+		return 0
+	}
+	return h
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	slicesHelper "github.com/vologzhan/maker-common/slices"
 	"github.com/vologzhan/maker/template"
+	"regexp"
 	"slices"
+	"strings"
 )
 
 type (
@@ -40,12 +42,17 @@ type (
 		Template *template.Imports
 		Parent   *File
 		Items    []*Import
+		Tail     []string // comment lines after the last import
+		style    importsStyle
 	}
 	Import struct {
 		Template *template.Import
 		Parent   *Imports
 		Name     []Stringer
 		Alias    []Stringer
+		Doc      []string // comment lines above the import
+		Comment  string   // comment at the end of the line
+		Group    int      // imports of a group are separated by an empty line, 0 if the import isn't read from the source
 	}
 	Word struct {
 		Template *template.Word
@@ -62,6 +69,14 @@ type (
 		Parent   Node
 		Value    string
 	}
+	// Joined is the line matched as joined from several source lines, e.g. a multi-line composite literal element.
+	// Its items keep the parent of the line, the original lines are written while the items render the same line.
+	Joined struct {
+		Parent Node
+		Items  []Stringer
+		Value  string // the joined line
+		Raw    string // the original lines
+	}
 )
 
 type Node interface {
@@ -124,6 +139,8 @@ func (n *Separator) GetTemplate() template.Node {
 	return n.Template
 }
 
+func (n *Joined) GetTemplate() template.Node { return nil }
+
 func (n *Dir) GetParent() Node       { return n.Parent }
 func (n *File) GetParent() Node      { return n.Parent }
 func (n *Template) GetParent() Node  { return n.Parent }
@@ -133,6 +150,7 @@ func (n *Import) GetParent() Node    { return n.Parent }
 func (n *Word) GetParent() Node      { return n.Parent }
 func (n *LineFeed) GetParent() Node  { return n.Parent }
 func (n *Separator) GetParent() Node { return n.Parent }
+func (n *Joined) GetParent() Node    { return n.Parent }
 
 func GetChildren(n Node) []Node {
 	var children []Node
@@ -170,6 +188,10 @@ func GetChildren(n Node) []Node {
 		for _, child := range n.Alias {
 			children = append(children, child)
 		}
+	case *Joined:
+		for _, child := range n.Items {
+			children = append(children, child)
+		}
 	}
 
 	return children
@@ -216,6 +238,13 @@ func findNearestNodeAndPath(src Node, tpl template.Node) (Node, []template.Node)
 						break
 					}
 				}
+			case *Template:
+				for _, node := range src.Items {
+					if node.GetTemplate() == p {
+						child = node
+						break
+					}
+				}
 			default:
 				panic(fmt.Sprintf("source: findChildByTemplate: unexpected parent node type '%T'", src))
 			}
@@ -231,6 +260,7 @@ func findNearestNodeAndPath(src Node, tpl template.Node) (Node, []template.Node)
 }
 
 func (n *Insert) SetValue(v string) error {
+	old := n.Value
 	n.Value = v
 
 	fs, err := upToFsNode(n)
@@ -242,14 +272,16 @@ func (n *Insert) SetValue(v string) error {
 		fs.SetFsStatus(FsStatusChanged)
 	}
 
-	if n.Template.Name != "type_go" {
-		return nil
-	}
-
 	file, ok := fs.(*File)
 	if !ok {
+		if n.Template.Name != "type_go" {
+			return nil
+		}
 		return errors.New("source: Insert.SetValue: expected file node")
 	}
+	if file.Template.Type != template.FileGo || old == v {
+		return nil
+	}
 
 	var imports *Imports
 	for _, node := range file.Content {
@@ -260,29 +292,29 @@ func (n *Insert) SetValue(v string) error {
 	}
 
 	if imports == nil {
+		if n.Template.Name != "type_go" {
+			return nil
+		}
 		return errors.New("source: Insert.SetValue: not found node imports")
 	}
 
-	imports.AddImportByTypeGo(n.Value)
+	imports.AddImportByTypeGo(v)
+	imports.removeUnusedImports(importsOfTypes(imports.Template.ByType, old))
 
 	return nil
 }
 
+// AddImportByTypeGo adds imports of qualified types of the value, e.g. "[]decimal.Decimal",
+// types are mapped to imports by the template config.
 func (n *Imports) AddImportByTypeGo(typeGo string) {
-	var nameInImport string
-	switch typeGo {
-	case "uuid.UUID":
-		nameInImport = "github.com/google/uuid"
-	case "time.Time":
-		nameInImport = "time"
-	case "json.RawMessage":
-		nameInImport = "encoding/json"
-	default:
-		return
+	for _, path := range importsOfTypes(n.Template.ByType, typeGo) {
+		n.addImport(path)
 	}
+}
 
+func (n *Imports) addImport(path string) {
 	for _, i := range n.Items {
-		if nameInImport == concat(i.Name) {
+		if path == concat(i.Name) {
 			return
 		}
 	}
@@ -292,17 +324,69 @@ func (n *Imports) AddImportByTypeGo(typeGo string) {
 		n,
 		make([]Stringer, 1),
 		nil,
+		nil,
+		"",
+		0,
 	}
 
 	imp.Name[0] = &Word{
 		nil,
 		imp,
-		nameInImport,
+		path,
 	}
 
 	n.Items = append(n.Items, imp)
 }
 
+// removeUnusedImports removes imports of the packages by qualifiers if the file doesn't use them anymore,
+// imports of the template are kept.
+func (n *Imports) removeUnusedImports(imports map[string]string) {
+	if len(imports) == 0 {
+		return
+	}
+
+	var buf strings.Builder
+	for _, node := range n.Parent.Content {
+		if _, ok := node.(*Imports); !ok {
+			buf.WriteString(node.String())
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, m := range qualifierUseRe.FindAllStringSubmatch(buf.String(), -1) {
+		used[m[1]] = true
+	}
+
+	for qualifier, path := range imports {
+		if used[qualifier] {
+			continue
+		}
+
+		n.Items = slices.DeleteFunc(n.Items, func(i *Import) bool {
+			return i.Template == nil && concat(i.Name) == path && (len(i.Alias) == 0 || concat(i.Alias) == qualifier)
+		})
+	}
+}
+
+var (
+	qualifiedTypeRe = regexp.MustCompile(`([A-Za-z_][\w]*)\.([A-Za-z_][\w]*)`)
+	qualifierUseRe  = regexp.MustCompile(`(?:^|[^\w.])([A-Za-z_]\w*)\.`)
+)
+
+// importsOfTypes returns import paths of qualified types of the value by qualifiers,
+// the exact type is looked up first, then all types of the package, e.g. "pgtype.*".
+func importsOfTypes(byType map[string]string, value string) map[string]string {
+	out := make(map[string]string)
+	for _, m := range qualifiedTypeRe.FindAllStringSubmatch(value, -1) {
+		if path, ok := byType[m[0]]; ok {
+			out[m[1]] = path
+		} else if path, ok := byType[m[1]+".*"]; ok {
+			out[m[1]] = path
+		}
+	}
+	return out
+}
+
 func DeleteNode(n Node) error {
 	if fs, ok := n.(Fs); ok {
 		switch fs.GetFsStatus() {
@@ -0,0 +1,60 @@
+package source
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vologzhan/maker/template"
+	"testing"
+	"testing/fstest"
+)
+
+func TestInsertSetValueImports(t *testing.T) {
+	fsys := fstest.MapFS{
+		".maker.yaml": {Data: []byte(`imports:
+  decimal.Decimal: github.com/shopspring/decimal
+  pgtype.*: github.com/jackc/pgx/v5/pgtype
+`)},
+		"{!entity-name}/model.go.t": {Data: []byte(`package model
+
+type ▶EntityName◀ struct {
+⏩	▶⬇AttributeName◀ ▶⬇Attribute➡GoType◀⏪
+}
+`)},
+	}
+	content := `package model
+
+import "github.com/shopspring/decimal"
+
+type User struct {
+	Price decimal.Decimal
+}
+`
+
+	file := mustParseTestFile(t, fsys, content)
+
+	var price *Insert
+	for _, item := range file.Content {
+		if tpl, ok := item.(*Template); ok {
+			for _, child := range GetChildren(tpl) {
+				if ins, ok := child.(*Insert); ok && ins.Template.Name == "go_type" {
+					price = ins
+				}
+			}
+		}
+	}
+	require.NotNil(t, price)
+
+	require.NoError(t, price.SetValue("[]pgtype.Numeric"))
+	assert.Contains(t, concat(file.Content), "import \"github.com/jackc/pgx/v5/pgtype\"\n")
+	assert.NotContains(t, concat(file.Content), "shopspring", "the unused import is removed")
+
+	require.NoError(t, price.SetValue("time.Time"))
+	assert.Contains(t, concat(file.Content), "import \"time\"\n", "default imports are kept without the config")
+}
+
+func TestInsertSetValueTypeGoOutOfFile(t *testing.T) {
+	dir := &Dir{nil, nil, nil, nil, "model", FsStatusNotChanged}
+	ins := &Insert{&template.Insert{Name: "type_go"}, dir, "", nil}
+	dir.Name = []Stringer{ins}
+	assert.EqualError(t, ins.SetValue("uuid.UUID"), "source: Insert.SetValue: expected file node")
+}
@@ -7,12 +7,6 @@ import (
 	"strings"
 )
 
-const (
-	stateBase = iota
-	stateExpectedImport
-	stateInImport
-)
-
 type templateLine struct {
 	nodes    []template.Node
 	imports  *template.Imports
@@ -21,109 +15,342 @@ type templateLine struct {
 }
 
 type sourceLine struct {
-	value string
-	next  *sourceLine
+	value   string
+	raw     string // original lines, differs from value if several lines are joined
+	number  int
+	next    *sourceLine
+	explain explanation // nil unless the content is parsed by Explain
 }
 
-func parseContent(content []byte, file *File) error {
-	srcLine := splitSrcToLines(content)
+func parseContent(content []byte, file *File, exp explanation) error {
+	switch file.Template.Type {
+	case template.FileYaml, template.FileEnv, template.FileJson, template.FileMarkdown, template.FileProto:
+		parseStructured(content, file, exp)
+		return nil
+	case template.FileSql:
+		if ins := wholeBodyInsert(file.Template.Content); ins != nil {
+			parseWholeBody(content, ins, file)
+			return nil
+		}
+	}
+
+	srcLine := splitFileToLines(content, file.Template)
+	exp.attach(srcLine)
 	tplLine := splitTplToLines(file.Template.Content)
-	var buf []string
-	state := stateBase
 
 	for ; srcLine != nil; srcLine = srcLine.next {
+		if tplLine != nil && tplLine.imports != nil && srcLine.value != "" {
+			last, err := readImports(srcLine, tplLine.imports, file)
+			if err != nil {
+				return err
+			}
+			tplLine = tplLine.next
+			if last != nil {
+				srcLine = last
+				continue
+			}
+		}
 		if tplLine == nil {
 			var tail []string
 			for ; srcLine != nil; srcLine = srcLine.next {
-				tail = append(tail, srcLine.value)
+				tail = append(tail, srcLine.raw)
+				explainLine(srcLine, "kept as is, the template has no more lines")
 			}
 			file.Content = append(file.Content, &Word{nil, file, strings.Join(tail, "\n")})
 
 			break
 		}
+		if srcLine.value == "" && srcLine.next == nil {
+			explainLine(srcLine, "end of file") // the final line feed is already written after the previous line
+			break
+		}
 		if srcLine.value == "" {
 			file.Content = append(file.Content, &LineFeed{nil, file, 1})
+			explainLine(srcLine, "empty line")
 			continue
 		}
 
-		if tplLine.imports != nil && state == stateBase {
-			state = stateExpectedImport
-		}
+		if tplLine.tplEntry != nil {
+			entry, last, matched, err := parseEntry(srcLine, tplLine.tplEntry, file)
+			if err != nil {
+				return err
+			}
+			if matched {
+				file.Content = append(file.Content, entry)
+				srcLine = last
+				continue
+			}
 
-		switch state {
-		case stateBase:
-		case stateExpectedImport:
-			if !strings.HasPrefix(srcLine.value, "import") {
-				err := parseImports(nil, tplLine.imports, file)
+			if next := tplLine.next; next != nil && next.tplEntry != nil {
+				entry, last, matched, err := parseEntry(srcLine, next.tplEntry, file)
 				if err != nil {
 					return err
 				}
-				tplLine = tplLine.next
-				state = stateBase
-				break
-			} else if strings.HasSuffix(srcLine.value, "(") {
-				state = stateInImport
-				continue
-			} else {
-				srcImp := strings.TrimPrefix(srcLine.value, "import")
-				srcImp = strings.TrimSpace(srcImp)
-				err := parseImports([]string{srcImp}, tplLine.imports, file)
+				if matched {
+					file.Content = append(file.Content, entry)
+					srcLine = last
+					tplLine = next
+					continue
+				}
+			} else if next != nil {
+				line, matched, err := parseLine(srcLine, next.nodes, file)
 				if err != nil {
 					return err
 				}
-				tplLine = tplLine.next
-				state = stateBase
-				continue
-			}
-		case stateInImport:
-			srcImp := strings.TrimSpace(srcLine.value)
-			if srcImp == "" {
-				continue
-			}
-			if srcImp != ")" {
-				buf = append(buf, srcImp)
-				continue
-			}
-			err := parseImports(buf, tplLine.imports, file)
-			if err != nil {
-				return err
+				if matched {
+					file.Content = append(file.Content, append(line, &LineFeed{nil, file, 1})...)
+					tplLine = next.next
+					continue
+				}
 			}
-			buf = nil
-			tplLine = tplLine.next
-			state = stateBase
+
+			file.Content = append(file.Content, &Word{nil, file, srcLine.raw}, &LineFeed{nil, file, 1})
+			explainLine(srcLine, "kept as is, matches neither the child template nor the next template line")
 			continue
 		}
 
-		line, matched, err := parse(srcLine.value, tplLine.nodes, file)
+		line, matched, err := parseLine(srcLine, tplLine.nodes, file)
 		if err != nil {
 			return err
 		}
-
-		if matched && tplLine.tplEntry != nil {
-			file.Content = append(file.Content, &Template{tplLine.tplEntry, file, line})
-			continue
+		if !matched && file.Template.Type == template.FileGoMod && matchGoModRequire(srcLine, tplLine.nodes) {
+			line, matched = []Stringer{&Word{nil, file, srcLine.raw}}, true
+			explainLine(srcLine, "matched the require of the template by the module path")
 		}
 		if matched {
-			file.Content = append(file.Content, append(line, &LineFeed{nil, file, 1})...)
 			tplLine = tplLine.next
+		} else {
+			explainLine(srcLine, "kept as is, does not match the template line")
+		}
+		file.Content = append(file.Content, append(line, &LineFeed{nil, file, 1})...)
+	}
+
+	return nil
+}
+
+// parseEntry matches a child template against the source starting from srcLine.
+// The template may span several lines and contain nested child templates,
+// each of them is matched repeatedly. Returns the last consumed source line.
+func parseEntry(srcLine *sourceLine, tpl *template.Template, parent Node) (*Template, *sourceLine, bool, error) {
+	if tpl.Block {
+		return parseBlock(srcLine, tpl, parent)
+	}
+
+	out := &Template{tpl, parent, nil}
+	var last *sourceLine
+	var buf []template.Node
+
+	for _, item := range tpl.Items {
+		switch item := item.(type) {
+		case *template.LineFeed:
+			for i := 0; i < item.Value; i++ {
+				if srcLine == nil {
+					return nil, nil, false, nil
+				}
+				line, matched, err := parseLine(srcLine, buf, out)
+				if err != nil || !matched {
+					return nil, nil, false, err
+				}
+				out.Items = append(out.Items, line...)
+				buf = nil
+				last = srcLine
+				srcLine = srcLine.next
+			}
+			out.Items = append(out.Items, &LineFeed{item, out, item.Value})
+		case *template.Template:
+			if !item.Entry {
+				buf = append(buf, item)
+				break
+			}
+			for srcLine != nil {
+				child, childLast, matched, err := parseEntry(srcLine, item, out)
+				if err != nil {
+					return nil, nil, false, err
+				}
+				if !matched {
+					break
+				}
+				out.Items = append(out.Items, child)
+				last = childLast
+				srcLine = childLast.next
+			}
+		default:
+			buf = append(buf, item)
+		}
+	}
+
+	if template.EndsWithEntry(tpl) {
+		return out, last, last != nil, nil
+	}
+
+	if srcLine == nil {
+		return nil, nil, false, nil
+	}
+	line, matched, err := parseLine(srcLine, buf, out)
+	if err != nil || !matched {
+		return nil, nil, false, err
+	}
+	out.Items = append(out.Items, line...)
+
+	return out, srcLine, true, nil
+}
+
+type blockLine struct {
+	nodes []template.Node
+	entry *template.Template
+}
+
+// parseBlock matches a block template against the source starting from srcLine.
+// The block is anchored by its first and last non-empty lines, lines between
+// them that don't match the template are kept as is, so the body can be edited by hand.
+func parseBlock(srcLine *sourceLine, tpl *template.Template, parent Node) (*Template, *sourceLine, bool, error) {
+	lines := splitBlockToLines(tpl)
+
+	first, last := -1, -1
+	for i, line := range lines {
+		if len(line.nodes) == 0 {
 			continue
 		}
-		if tplLine.tplEntry != nil && tplLine.next != nil {
-			next := tplLine.next
-			line, matched, err := parse(srcLine.value, next.nodes, file)
+		if first == -1 {
+			first = i
+		}
+		last = i
+	}
+	if first == -1 {
+		return nil, nil, false, fmt.Errorf("source: parseBlock: block has no lines to match")
+	}
+
+	out := &Template{tpl, parent, nil}
+	var lastSrc *sourceLine
+
+	for i := 0; i < first; i++ {
+		// leading empty lines are optional, they may be already read as line feeds of the parent
+		if srcLine == nil || srcLine.value != "" {
+			break
+		}
+		out.Items = append(out.Items, &LineFeed{nil, out, 1})
+		lastSrc = srcLine
+		srcLine = srcLine.next
+	}
+
+	if srcLine == nil {
+		return nil, nil, false, nil
+	}
+	header, matched, err := parseLine(srcLine, lines[first].nodes, out)
+	if err != nil || !matched {
+		return nil, nil, false, err
+	}
+	out.Items = append(out.Items, append(header, &LineFeed{nil, out, 1})...)
+	lastSrc = srcLine
+	srcLine = srcLine.next
+
+	next := first + 1
+	for first < last {
+		if srcLine == nil {
+			return nil, nil, false, nil
+		}
+
+		found := false
+		for i := next; i < last && !found; i++ {
+			if lines[i].entry != nil {
+				child, childLast, matched, err := parseEntry(srcLine, lines[i].entry, out)
+				if err != nil {
+					return nil, nil, false, err
+				}
+				if matched {
+					out.Items = append(out.Items, child)
+					lastSrc = childLast
+					srcLine = childLast.next
+					next = i
+					found = true
+				}
+				continue
+			}
+			if len(lines[i].nodes) == 0 {
+				continue
+			}
+
+			line, matched, err := parseLine(srcLine, lines[i].nodes, out)
 			if err != nil {
-				return err
+				return nil, nil, false, err
 			}
 			if matched {
-				file.Content = append(file.Content, append(line, &LineFeed{nil, file, 1})...)
-				tplLine = next.next
+				out.Items = append(out.Items, append(line, &LineFeed{nil, out, 1})...)
+				lastSrc = srcLine
+				srcLine = srcLine.next
+				next = i + 1
+				found = true
+			}
+		}
+		if found {
+			continue
+		}
+
+		line, matched, err := parseLine(srcLine, lines[last].nodes, out)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if matched {
+			out.Items = append(out.Items, append(line, &LineFeed{nil, out, 1})...)
+			lastSrc = srcLine
+			srcLine = srcLine.next
+			break
+		}
+
+		out.Items = append(out.Items, &Word{nil, out, srcLine.raw}, &LineFeed{nil, out, 1})
+		explainLine(srcLine, "kept as is inside the block")
+		lastSrc = srcLine
+		srcLine = srcLine.next
+	}
+
+	for i := last + 1; i < len(lines) && srcLine != nil && srcLine.value == ""; i++ {
+		out.Items = append(out.Items, &LineFeed{nil, out, 1})
+		lastSrc = srcLine
+		srcLine = srcLine.next
+	}
+
+	return out, lastSrc, true, nil
+}
+
+func splitBlockToLines(tpl *template.Template) []blockLine {
+	var out []blockLine
+	var buf []template.Node
+
+	for _, item := range tpl.Items {
+		switch item := item.(type) {
+		case *template.LineFeed:
+			out = append(out, blockLine{buf, nil})
+			for i := 1; i < item.Value; i++ {
+				out = append(out, blockLine{})
+			}
+			buf = nil
+			continue
+		case *template.Template:
+			if item.Entry {
+				out = append(out, blockLine{nil, item})
 				continue
 			}
 		}
-		file.Content = append(file.Content, append(line, &LineFeed{nil, file, 1})...)
+
+		buf = append(buf, item)
 	}
 
-	return nil
+	return out
+}
+
+func splitFileToLines(content []byte, tpl *template.File) *sourceLine {
+	switch tpl.Type {
+	case template.FileGo:
+		return splitGoSrcToLines(content)
+	case template.FileSql:
+		return splitSqlToLines(content)
+	case template.FileProto:
+		return splitProtoToLines(content)
+	case template.FileTs:
+		return splitTsToLines(content)
+	default:
+		return splitSrcToLines(content)
+	}
 }
 
 func splitSrcToLines(content []byte) *sourceLine {
@@ -132,8 +359,8 @@ func splitSrcToLines(content []byte) *sourceLine {
 	var first *sourceLine
 	var prev *sourceLine
 
-	for _, line := range lines {
-		cur := &sourceLine{line, nil}
+	for i, line := range lines {
+		cur := &sourceLine{line, line, i + 1, nil, nil}
 		if prev != nil {
 			prev.next = cur
 		}
@@ -150,22 +377,28 @@ func splitTplToLines(content []template.Node) *templateLine {
 	zeroLine := &templateLine{}
 	prev := zeroLine
 	var buf []template.Node
-	var imps *template.Imports
 
 	for _, node := range content {
 		switch n := node.(type) {
 		case *template.LineFeed:
-			if len(buf) > 0 || imps != nil {
-				prev = newTemplateLine(buf, prev, imps, nil)
+			if len(buf) > 0 {
+				prev = newTemplateLine(buf, prev, nil, nil)
 				buf = nil
-				imps = nil
 			}
 			continue
 		case *template.Imports:
-			imps = n
+			if len(buf) > 0 {
+				prev = newTemplateLine(buf, prev, nil, nil)
+				buf = nil
+			}
+			prev = newTemplateLine(nil, prev, n, nil) // imports take their own lines in the source
 			continue
 		case *template.Template:
 			if n.Entry {
+				if len(buf) > 0 {
+					prev = newTemplateLine(buf, prev, nil, nil)
+					buf = nil
+				}
 				prev = newTemplateLine(n.Items, prev, nil, n)
 				continue
 			}
@@ -174,8 +407,8 @@ func splitTplToLines(content []template.Node) *templateLine {
 		buf = append(buf, node)
 	}
 
-	if len(buf) > 0 || imps != nil {
-		prev = newTemplateLine(buf, prev, imps, nil)
+	if len(buf) > 0 {
+		prev = newTemplateLine(buf, prev, nil, nil)
 	}
 
 	return zeroLine.next
@@ -189,19 +422,59 @@ func newTemplateLine(nodes []template.Node, prev *templateLine, imps *template.I
 	return tplLine
 }
 
+// readImports reads the import declarations of the file type starting from the line,
+// returns the last line of the declarations or nil if the line doesn't start them.
+func readImports(first *sourceLine, tpl *template.Imports, f *File) (*sourceLine, error) {
+	switch f.Template.Type {
+	case template.FileTs:
+		return readTsImports(first, tpl, f)
+	default:
+		return readGoImports(first, tpl, f)
+	}
+}
+
 func parseImports(imps []string, tpl *template.Imports, f *File) error {
-	srcImps := &Imports{tpl, f, nil}
+	srcImps := &Imports{tpl, f, nil, nil, goImports{}}
 	f.Content = append(f.Content, srcImps)
 	f.Content = append(f.Content, &LineFeed{nil, f, 1})
 
-	for i := range imps {
-		imps[i] = strings.Replace(imps[i], "\"", "", 2)
-	}
-
 	tplImps := getSortedTplImports(tpl)
 
+	group := 1
+	blank := false
+	inComment := false
+	var doc []string
 	for _, imp := range imps {
-		nameAndAlias := strings.Split(imp, " ")
+		line := strings.TrimRight(strings.TrimPrefix(imp, "\t"), " \t")
+		imp = strings.TrimSpace(imp)
+		if inComment {
+			doc = append(doc, line)
+			inComment = !strings.Contains(imp, "*/")
+			continue
+		}
+		if strings.HasPrefix(imp, "/*") {
+			doc = append(doc, imp)
+			inComment = !strings.Contains(imp, "*/")
+			continue
+		}
+		if imp == "" {
+			blank = len(srcImps.Items) > 0
+			continue
+		}
+		if strings.HasPrefix(imp, "//") {
+			doc = append(doc, imp)
+			continue
+		}
+		if blank {
+			group++
+			blank = false
+		}
+
+		imp, comment := splitImportComment(imp)
+		imp = strings.Replace(imp, "\"", "", 2)
+		imp = strings.Replace(imp, "`", "", 2)
+
+		nameAndAlias := strings.Fields(imp)
 
 		var name string
 		var alias string
@@ -212,8 +485,9 @@ func parseImports(imps []string, tpl *template.Imports, f *File) error {
 			name = nameAndAlias[0]
 		}
 
-		srcImp := &Import{nil, srcImps, nil, nil}
+		srcImp := &Import{nil, srcImps, nil, nil, doc, comment, group}
 		srcImps.Items = append(srcImps.Items, srcImp)
+		doc = nil
 
 		for i, tplImp := range tplImps {
 			srcName, matched, err := parse(name, tplImp.Name, srcImp)
@@ -259,9 +533,27 @@ func parseImports(imps []string, tpl *template.Imports, f *File) error {
 		}
 	}
 
+	srcImps.Tail = doc
+
 	return nil
 }
 
+// splitImportComment splits an import spec and a comment after it, the comment starts after the closing quote
+// of the import path, so it may contain quotes itself.
+func splitImportComment(imp string) (string, string) {
+	start := strings.IndexAny(imp, "\"`")
+	if start == -1 {
+		return imp, ""
+	}
+	end := strings.IndexByte(imp[start+1:], imp[start])
+	if end == -1 {
+		return imp, ""
+	}
+	end += start + 2
+
+	return imp[:end], strings.TrimSpace(imp[end:])
+}
+
 func getSortedTplImports(imps *template.Imports) []*template.Import {
 	var head []*template.Import
 	var tail []*template.Import
@@ -298,6 +590,22 @@ func mustParse(str string, tplNodes []template.Node, parent Node) []Stringer {
 	return nodes
 }
 
+func parseLine(srcLine *sourceLine, tplNodes []template.Node, parent Node) ([]Stringer, bool, error) {
+	nodes, matched, err := parse(srcLine.value, tplNodes, parent)
+	if err == nil {
+		explainAttempt(srcLine, tplNodes, nodes, matched)
+	}
+	if err == nil && srcLine.raw != srcLine.value {
+		if matched {
+			nodes = []Stringer{&Joined{parent, nodes, srcLine.value, srcLine.raw}}
+		} else {
+			nodes = []Stringer{&Word{nil, parent, srcLine.raw}} // keep the original formatting of joined lines
+		}
+	}
+
+	return nodes, matched, err
+}
+
 func parse(str string, tplNodes []template.Node, parent Node) ([]Stringer, bool, error) {
 	reg, err := compileRegexp(tplNodes)
 	if err != nil {
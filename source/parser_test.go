@@ -0,0 +1,766 @@
+package source
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vologzhan/maker/template"
+	"io/fs"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseContentNestedEntry(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/relations.txt.t": {Data: []byte(`relations of ▶EntityName◀:
+⏩- ▶⬇RelationName◀ joins
+⏩  ▶⬇JoinName◀ = ▶⬇Join➡ColumnFk◀⏪⏪
+end
+`)},
+	}
+	content := `relations of User:
+- Profile joins
+  Id = user_id
+  Name = user_name
+- Role joins
+end
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	assert.Equal(t, content, concat(file.Content))
+
+	var relations []*Template
+	for _, item := range file.Content {
+		if tpl, ok := item.(*Template); ok {
+			relations = append(relations, tpl)
+		}
+	}
+	require.Equal(t, 2, len(relations))
+
+	var joins []*Template
+	for _, item := range relations[0].Items {
+		if tpl, ok := item.(*Template); ok {
+			joins = append(joins, tpl)
+		}
+	}
+	require.Equal(t, 2, len(joins))
+	assert.Equal(t, "  Name = user_name\n", joins[1].String())
+	assert.Equal(t, "- Role joins\n", relations[1].String())
+}
+
+func mustParseTestFile(t *testing.T, fsys fs.FS, content string) *File {
+	root, err := template.New(fsys, "")
+	require.NoError(t, err)
+
+	tplFile := firstTemplateFile(root.Entrypoints[0])
+	require.NotNil(t, tplFile)
+
+	file := &File{tplFile, nil, nil, nil, "", FsStatusNotRead}
+	require.NoError(t, parseContent([]byte(content), file, nil))
+
+	return file
+}
+
+func firstTemplateFile(tpl template.Node) *template.File {
+	switch tpl := tpl.(type) {
+	case *template.File:
+		return tpl
+	case *template.Dir:
+		for _, item := range tpl.Items {
+			if file := firstTemplateFile(item); file != nil {
+				return file
+			}
+		}
+	}
+	return nil
+}
+
+func TestParseContentBlock(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/repository.go.t": {Data: []byte(`package repository
+
+import "errors"
+
+⏬
+func (r *Repository) Get▶⬇EntityName◀(id int) (*▶EntityName◀, error) {
+	var out ▶EntityName◀
+	err := r.db.Get(&out, id)
+	return &out, err
+}
+⏫
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+`)},
+	}
+	content := `package repository
+
+import "errors"
+
+func (r *Repository) GetUser(id int) (*User, error) {
+	var out User
+	if id == 0 {
+		return nil, nil
+	}
+	err := r.db.Get(&out, id)
+	return &out, err
+}
+
+func (r *Repository) GetRole(id int) (*Role, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+`
+
+	file := mustParseTestFile(t, fsys, content)
+
+	var blocks []*Template
+	for _, item := range file.Content {
+		if tpl, ok := item.(*Template); ok {
+			blocks = append(blocks, tpl)
+		}
+	}
+	require.Equal(t, 2, len(blocks))
+	assert.Equal(t, `func (r *Repository) GetUser(id int) (*User, error) {
+	var out User
+	if id == 0 {
+		return nil, nil
+	}
+	err := r.db.Get(&out, id)
+	return &out, err
+}
+`, blocks[0].String())
+	assert.Equal(t, `func (r *Repository) GetRole(id int) (*Role, error) {
+	return nil, errors.New("not implemented")
+}
+`, blocks[1].String())
+}
+
+func TestExplain(t *testing.T) {
+	tpl, err := template.New(fstest.MapFS{
+		"{!entity-name}/fields.txt.t": {Data: []byte("fields of ▶EntityName◀:\n⏩- ▶⬇AttributeName◀ ▶⬇Attribute➡TypeGo◀⏪\nend\n")},
+	}, "")
+	require.NoError(t, err)
+
+	tplFile, err := FindTemplateFile(tpl.Entrypoints[0].(*template.Dir), "user/fields.txt")
+	require.NoError(t, err)
+
+	var out strings.Builder
+	err = Explain(&out, tplFile, []byte("fields of User:\n- Id int\n* Name string\nend\n"))
+	require.NoError(t, err)
+
+	assert.Contains(t, out.String(), "2: - Id int\n"+
+		"\ttemplate: - ▶⬇attribute➡name◀ ▶⬇attribute➡type_go◀\n"+
+		"\tregexp: ^(-)([\\t ]+)([^/]+?)([\\t ]+)([^/]+?)$\n"+
+		"\tmatched: attribute.name=\"Id\", attribute.type_go=\"int\"\n")
+	assert.Contains(t, out.String(), "3: * Name string\n")
+	assert.Contains(t, out.String(), "\tkept as is, matches neither the child template nor the next template line\n4: end\n")
+}
+
+func TestExplainMarkers(t *testing.T) {
+	tpl, err := template.New(fstest.MapFS{
+		template.ConfigFile: {Data: []byte(`markers:
+  content:
+    template_start: "{{"
+    template_end: "}}"
+    child_start: "[["
+    child_end: "]]"
+    key: "@"
+    separator: "->"
+`)},
+		"{!entity-name}/fields.txt.t": {Data: []byte("fields of {{EntityName}}:\n[[- {{@AttributeName}}]]\nend\n")},
+	}, "")
+	require.NoError(t, err)
+
+	tplFile, err := FindTemplateFile(tpl.Entrypoints[0].(*template.Dir), "user/fields.txt")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	outs := make([]strings.Builder, 4)
+	for i := range outs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, Explain(&outs[i], tplFile, []byte("fields of User:\n- Id\nend\n")))
+		}()
+	}
+	wg.Wait()
+
+	for i := range outs {
+		assert.Contains(t, outs[i].String(), "2: - Id\n"+
+			"\ttemplate: - {{@attribute->name}}\n")
+		assert.Equal(t, 1, strings.Count(outs[i].String(), "\tmatched: attribute.name=\"Id\"\n"))
+	}
+}
+
+func TestParseContentGoMultiLineNodes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}.go.t": {Data: []byte(`package dto
+
+type ▶EntityName◀ struct {
+⏩	▶⬇AttributeName◀ ▶⬇Attribute➡TypeGo◀⏪
+}
+
+var ▶EntityName◀Columns = map[string]string{
+⏩	"▶⬇attribute_name◀": ▶⬇Attribute➡TypeDb◀,⏪
+}
+`)},
+	}
+	content := `package dto
+
+type User struct {
+	Id      int
+	Handler func(
+		ctx any,
+	) error
+}
+
+var UserColumns = map[string]string{
+	"id": "int",
+	"handler": varchar(
+		255,
+	),
+}
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	out, err := buildContent(file)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(out), "the original lines of joined nodes are kept")
+
+	var types []*Insert
+	for _, item := range file.Content {
+		tpl, ok := item.(*Template)
+		if !ok {
+			continue
+		}
+		for _, child := range GetChildren(tpl) {
+			for _, node := range append([]Node{child}, GetChildren(child)...) {
+				if ins, ok := node.(*Insert); ok && strings.HasPrefix(ins.Template.Name, "type_") {
+					types = append(types, ins)
+				}
+			}
+		}
+	}
+	require.Equal(t, 4, len(types))
+	assert.Equal(t, "func(ctx any) error", types[1].Value)
+	assert.Equal(t, "varchar(255)", types[3].Value)
+
+	require.NoError(t, types[1].SetValue("func() error"))
+	out, err = buildContent(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "type User struct {\n\tId      int\n\tHandler func() error\n}\n", "a changed node is written in one line")
+	assert.Contains(t, string(out), "\t\"handler\": varchar(\n\t\t255,\n\t),\n")
+
+	invalid := "package dto\n\ntype User struct {\n\tHandler func(\n"
+	assert.Contains(t, concat(mustParseTestFile(t, fsys, invalid).Content), "\tHandler func(\n") // not valid Go is read by lines
+}
+
+func TestSplitGoSrcToLines(t *testing.T) {
+	content := `package dto
+
+type User struct {
+	Handler func(
+		ctx any,
+	) error
+	Filter func(
+		// the context of the request
+		ctx any,
+	) bool
+	Id int // maker:pk
+}
+`
+
+	var values []string
+	for line := splitGoSrcToLines([]byte(content)); line != nil; line = line.next {
+		values = append(values, line.value)
+	}
+	assert.Equal(t, []string{
+		"package dto",
+		"",
+		"type User struct {",
+		"\tHandler func(ctx any) error",
+		"\tFilter func(", // a comment inside the node would swallow the joined lines, so they are kept
+		"\t\t// the context of the request",
+		"\t\tctx any,",
+		"\t) bool",
+		"\tId int // maker:pk",
+		"}",
+		"",
+	}, values)
+}
+
+func TestParseContentGoImportGroups(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/model.go.t": {Data: []byte(`package model
+`)},
+	}
+	content := `package model
+
+import (
+	"fmt"
+	. "strings"
+
+	/*
+		the driver is registered on init
+	*/
+	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal" // money, see "docs"
+	// "github.com/pkg/errors"
+)
+
+var _ = fmt.Sprint(decimal.Zero, ToUpper(""))
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	block, _, _ := strings.Cut(strings.TrimPrefix(content, "package model\n\n"), "\n\nvar")
+	assert.Contains(t, concat(file.Content), block)
+
+	var imports *Imports
+	for _, item := range file.Content {
+		if imps, ok := item.(*Imports); ok {
+			imports = imps
+		}
+	}
+	require.NotNil(t, imports)
+	imports.AddImportByTypeGo("map[uuid.UUID]time.Time")
+	assert.Equal(t, `import (
+	"fmt"
+	. "strings"
+	"time"
+
+	/*
+		the driver is registered on init
+	*/
+	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal" // money, see "docs"
+	"github.com/google/uuid"
+	// "github.com/pkg/errors"
+)
+`, imports.String())
+}
+
+func TestSplitTplToLines(t *testing.T) {
+	imports := &template.Imports{}
+	first := splitTplToLines([]template.Node{
+		&template.Word{Value: "import ("},
+		imports,
+		&template.Word{Value: ")"},
+		&template.LineFeed{Value: 1},
+	})
+
+	var lines []*templateLine
+	for line := first; line != nil; line = line.next {
+		lines = append(lines, line)
+	}
+	require.Equal(t, 3, len(lines), "imports take their own line, the words around them don't")
+	assert.Equal(t, []template.Node{&template.Word{Value: "import ("}}, lines[0].nodes)
+	assert.Nil(t, lines[0].imports)
+	assert.Same(t, imports, lines[1].imports)
+	assert.Empty(t, lines[1].nodes)
+	assert.Equal(t, []template.Node{&template.Word{Value: ")"}}, lines[2].nodes)
+}
+
+func TestParseContentYaml(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/compose.yaml.t": {Data: []byte(`services:
+⏩  ▶⬇attribute-name◀:
+    image: ▶⬇Attribute➡TypeGo◀⏪
+volumes:
+  data:
+`)},
+	}
+	content := `volumes:
+  data:
+services:
+  db:
+    image: postgres
+
+  app:
+    ports: 80
+    image: app:1
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	assert.Equal(t, content, concat(file.Content))
+
+	images := make(map[string]string)
+	for _, item := range file.Content {
+		tpl, ok := item.(*Template)
+		if !ok {
+			continue
+		}
+
+		var values []string
+		for _, child := range GetChildren(tpl) {
+			if ins, ok := child.(*Insert); ok {
+				values = append(values, ins.Value)
+			}
+		}
+		require.Equal(t, 2, len(values))
+		images[values[0]] = values[1]
+	}
+	assert.Equal(t, map[string]string{"db": "postgres", "app": "app:1"}, images)
+}
+
+func TestParseContentYamlCompactSequence(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/compose.yaml.t": {Data: []byte(`volumes:
+- data
+services:
+⏩- ▶⬇attribute-name◀⏪
+`)},
+	}
+	content := `services:
+- app
+- db
+volumes:
+- data
+- cache
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	assert.Equal(t, content, concat(file.Content))
+
+	var names []string
+	for _, item := range file.Content {
+		tpl, ok := item.(*Template)
+		if !ok {
+			continue
+		}
+		for _, child := range GetChildren(tpl) {
+			if ins, ok := child.(*Insert); ok {
+				names = append(names, ins.Value)
+			}
+		}
+	}
+	assert.Equal(t, []string{"app", "db"}, names, "items at the indentation of the key belong to the key, 'cache' is kept as is")
+}
+
+func TestParseContentYamlMultiLine(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/compose.yaml.t": {Data: []byte(`⏩▶⬇attribute-name◀:
+  image: ▶⬇Attribute➡TypeGo◀⏪
+`)},
+	}
+	content := `db: |
+  image: postgres
+app:
+  image: app:1
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	assert.Equal(t, content, concat(file.Content))
+
+	var images []string
+	for _, item := range file.Content {
+		for _, child := range GetChildren(item) {
+			if ins, ok := child.(*Insert); ok && ins.Template.Name == "type_go" {
+				images = append(images, ins.Value)
+			}
+		}
+	}
+	assert.Equal(t, []string{"app:1"}, images, "lines of the block scalar are not matched")
+}
+
+func TestIsYamlMultiLineValue(t *testing.T) {
+	for line, expected := range map[string]bool{
+		"key: |":                 true,
+		"- >-":                   true,
+		"key: |2 # comment":      true,
+		"key: a|b":               false,
+		"key: [a,":               true,
+		"key: {a: [1, 2]}":       false,
+		"key: \"open":            true,
+		"key: 'it''s'":           false,
+		"key: it's":              false,
+		"key: \"a # [\" # [":     false,
+		"key: value # comment [": false,
+	} {
+		assert.Equal(t, expected, isYamlMultiLineValue(line), line)
+	}
+}
+
+func TestParseContentMarkdown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/README.md.t": {Data: []byte(`# ▶entity-name◀
+
+## Attributes
+
+| Name | Type |
+|------|------|
+⏩| ▶⬇attribute-name◀ | ▶⬇Attribute➡TypeGo◀ |⏪
+`)},
+	}
+	content := `# user
+
+## Notes
+
+| id | written by hand |
+
+## Attributes
+
+Generated, do not edit the table.
+
+| Name | Type |
+|------|------|
+| id | int |
+| name | string |
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	assert.Equal(t, content, concat(file.Content))
+
+	types := make(map[string]string)
+	rows := 0
+	for _, item := range file.Content {
+		tpl, ok := item.(*Template)
+		if !ok {
+			continue
+		}
+
+		var values []string
+		for _, child := range GetChildren(tpl) {
+			if ins, ok := child.(*Insert); ok {
+				values = append(values, ins.Value)
+			}
+		}
+		require.Equal(t, 2, len(values))
+		types[values[0]] = values[1]
+		rows++
+	}
+	assert.Equal(t, 2, rows, "rows of other sections are not owned")
+	assert.Equal(t, map[string]string{"id": "int", "name": "string"}, types)
+}
+
+func TestParseContentProto(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/api.proto.t": {Data: []byte(`syntax = "proto3";
+
+message ▶EntityName◀ {
+⏩  ▶⬇attribute-type-proto◀ ▶⬇attribute-name◀ = ▶⬇Attribute➡ProtoNumber◀;⏪
+}
+`)},
+	}
+	content := `syntax = "proto3";
+
+message User {
+  option (table) = 12;
+  reserved 4;
+  string id = 1;
+  string name = 2 [
+    deprecated = true
+  ];
+  int32 age = 3 [default = 18];
+  int64 legacy = 6; // written by hand
+}
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	assert.Equal(t, content, concat(file.Content))
+
+	var fields []*Template
+	for _, item := range file.Content {
+		if tpl, ok := item.(*Template); ok {
+			fields = append(fields, tpl)
+		}
+	}
+	require.Equal(t, 2, len(fields), "the fields with options are kept as is")
+
+	var numbers []string
+	for _, name := range []string{"active", "deleted"} {
+		entry, err := CreateEntry(fields[0].Template, file)
+		require.NoError(t, err)
+		for _, child := range GetChildren(entry) {
+			if ins, ok := child.(*Insert); ok {
+				value := map[string]string{"type_proto": "bool", "name": name}[ins.Template.Name]
+				if ins.Template.Name == "proto_number" {
+					value = InitialValue(ins)
+					numbers = append(numbers, value)
+				}
+				require.NoError(t, ins.SetValue(value))
+			}
+		}
+	}
+	assert.Equal(t, []string{"7", "8"}, numbers, "numbers follow the fields and reserved numbers of the message")
+
+	built, err := buildContent(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(built), "  int64 legacy = 6; // written by hand\n  bool active = 7;\n  bool deleted = 8;\n}\n")
+}
+
+func TestProtoNumbers(t *testing.T) {
+	body := `
+  option (table) = 12;
+  reserved 2, 9 to 11;
+  string id = 1 [(validate.rules).string.min_len = 30];
+  int32 age = 3 [default = 18];
+  map<string, int32> tags = 4;
+  repeated string roles = 5; oneof kind { string a = 6; }
+`
+	assert.Equal(t, []int{1, 3, 4, 5, 6, 2, 11}, protoNumbers(body))
+}
+
+func TestParseContentTs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/api.ts.t": {Data: []byte(`import { Client } from './client';
+
+export interface ▶EntityName◀ {
+⏩  ▶⬇attributeName◀: ▶⬇Attribute➡TypeTs◀;⏪
+}
+`)},
+	}
+	content := `import {
+  Client,
+} from './client';
+
+export interface User {
+  id: string;
+  createdAt: Date;
+}
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	assert.Equal(t, content, concat(file.Content), "the matched import keeps its lines")
+
+	types := make(map[string]string)
+	for _, item := range file.Content {
+		tpl, ok := item.(*Template)
+		if !ok {
+			continue
+		}
+
+		var values []string
+		for _, child := range GetChildren(tpl) {
+			if ins, ok := child.(*Insert); ok {
+				values = append(values, ins.Value)
+			}
+		}
+		require.Equal(t, 2, len(values))
+		types[values[0]] = values[1]
+	}
+	assert.Equal(t, map[string]string{"id": "string", "createdAt": "Date"}, types)
+}
+
+func TestSplitTsToLines(t *testing.T) {
+	content := `const re = /[(']+\/x/g;
+const half = (total / 2) / count;
+if (/^\(/.test(name)) {
+  call(a,
+    b);
+}
+`
+
+	var values []string
+	for line := splitTsToLines([]byte(content)); line != nil; line = line.next {
+		values = append(values, line.value)
+	}
+	assert.Equal(t, []string{
+		`const re = /[(']+\/x/g;`,
+		"const half = (total / 2) / count;",
+		`if (/^\(/.test(name)) {`,
+		"  call(a, b);",
+		"}",
+		"",
+	}, values)
+}
+
+func TestParseContentEnv(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/.env.t": {Data: []byte(`POSTGRES_HOST=localhost
+POSTGRES_USER=▶ENTITY_NAME◀
+⏩FEATURE_▶⬇ATTRIBUTE_NAME◀=▶⬇Attribute➡TypeGo◀⏪
+⏩LIMIT_▶⬇ATTRIBUTE_NAME◀=100⏪
+`)},
+	}
+	content := `# local overrides
+POSTGRES_USER=USER
+FEATURE_ID=on
+DEBUG=1
+POSTGRES_HOST=db
+FEATURE_NAME=off
+LIMIT_NAME=500
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	assert.Equal(t, content, concat(file.Content))
+
+	var raw, flags []string
+	for _, item := range file.Content {
+		switch item := item.(type) {
+		case *Word:
+			if item.Template == nil {
+				raw = append(raw, item.Value)
+			}
+		case *Template:
+			flags = append(flags, item.String())
+		}
+	}
+	assert.Equal(t, []string{"# local overrides", "DEBUG=1", "db"}, raw, "the changed value of the template variable is kept")
+	assert.Equal(t, []string{"FEATURE_ID=on\n", "FEATURE_NAME=off\n", "LIMIT_NAME=500\n"}, flags)
+}
+
+func TestParseContentSql(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{!entity-name}/create.sql.t": {Data: []byte(`CREATE TABLE ▶entity_name◀ (
+⏩    ▶⬇attribute_name◀ ▶⬇Attribute➡TypeDb◀,⏪
+    PRIMARY KEY (id)
+);
+`)},
+	}
+	content := `CREATE TABLE user (
+    id serial,
+    about text DEFAULT 'first line
+second line',
+    name text,
+    PRIMARY KEY (id)
+);
+`
+
+	file := mustParseTestFile(t, fsys, content)
+	assert.Equal(t, content, concat(file.Content))
+
+	var columns []string
+	for _, item := range file.Content {
+		if tpl, ok := item.(*Template); ok {
+			columns = append(columns, tpl.String())
+		}
+	}
+	assert.Equal(t, []string{"    id serial,\n", "    about text DEFAULT 'first line\nsecond line',\n", "    name text,\n"}, columns)
+}
+
+func TestSplitSqlToLines(t *testing.T) {
+	tests := []struct {
+		name, content string
+		expected      []string
+	}{
+		{"doubled quotes", "SELECT 'it''s\n', \"a\"\"\nb\" -- \"\nFROM t;", []string{"SELECT 'it''s\n', \"a\"\"\nb\" -- \"", "FROM t;"}},
+		{"dollar quote with quotes", "CREATE FUNCTION f() AS $body$\nSELECT 'it''s', \"a\n$$ ' $$\n$body$;\nSELECT 1;", []string{"CREATE FUNCTION f() AS $body$\nSELECT 'it''s', \"a\n$$ ' $$\n$body$;", "SELECT 1;"}},
+		{"E-string", "SELECT E'it\\'s\n', e'\\\\';\nSELECT 'a\\';", []string{"SELECT E'it\\'s\n', e'\\\\';", "SELECT 'a\\';"}},
+		{"nested comment", "/* a /* b */ '\n */ SELECT 1;\nSELECT 2;", []string{"/* a /* b */ '\n */ SELECT 1;", "SELECT 2;"}},
+		{"dollar in identifier and parameter", "SELECT a$b$ FROM t WHERE id = $1\nAND c = '$x$';", []string{"SELECT a$b$ FROM t WHERE id = $1", "AND c = '$x$';"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var lines []string
+			for line := splitSqlToLines([]byte(test.content)); line != nil; line = line.next {
+				lines = append(lines, line.value)
+			}
+			assert.Equal(t, test.expected, lines)
+		})
+	}
+}
+
+func TestSqlTokens(t *testing.T) {
+	var kinds []sqlTokenKind
+	for _, tok := range sqlTokens("SELECT E'\\'' $f$ ' $f$;\n") {
+		kinds = append(kinds, tok.kind)
+	}
+	assert.Equal(t, []sqlTokenKind{
+		sqlTokenWord, sqlTokenSpace, sqlTokenString, sqlTokenSpace, sqlTokenDollarQuote, sqlTokenSymbol, sqlTokenLineFeed,
+	}, kinds)
+}
@@ -0,0 +1,298 @@
+package source
+
+import (
+	"github.com/vologzhan/maker/template"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type protoState int
+
+const (
+	protoStateBase protoState = iota
+	protoStateString
+	protoStateLineComment
+	protoStateBlockComment
+)
+
+// protoReservedMin and protoReservedMax are the range of field numbers reserved for the protobuf implementation.
+const (
+	protoReservedMin = 19000
+	protoReservedMax = 19999
+)
+
+var (
+	// protoFieldRe matches the declaration of a field before its number: the optional label, the type and the name.
+	protoFieldRe       = regexp.MustCompile(`^\s*(?:(?:repeated|optional|required)\s+)?(map<[^>]*>|[\w.]+)\s+\w+\s*=\s*$`)
+	protoFieldNumberRe = regexp.MustCompile(`^\s*(?:(?:repeated|optional|required)\s+)?(map<[^>]*>|[\w.]+)\s+\w+\s*=\s*(\d+)\s*(?:\[[^\]]*\])?\s*$`)
+	protoReservedRe    = regexp.MustCompile(`\breserved\s+([^;]*);`)
+	protoCommentRe     = regexp.MustCompile(`//[^\n]*|/\*[\s\S]*?\*/`)
+)
+
+// splitProtoToLines splits protobuf source to statements with the tokenizer, a line break inside a string,
+// a block comment, field options in brackets or parentheses doesn't end the line.
+// Lines of a statement are joined to one line with single spaces.
+func splitProtoToLines(content []byte) *sourceLine {
+	s := string(content)
+	zeroLine := &sourceLine{}
+	prev := zeroLine
+
+	state := protoStateBase
+	var quote byte
+	depth := 0
+	comment := false // a line comment inside the statement, lines can't be joined
+	start, number, lineNumber := 0, 1, 1
+
+	for i := 0; i < len(s); i++ {
+		switch state {
+		case protoStateBase:
+			switch {
+			case s[i] == '"' || s[i] == '\'':
+				state = protoStateString
+				quote = s[i]
+			case strings.HasPrefix(s[i:], "//"):
+				state = protoStateLineComment
+				comment = comment || depth > 0
+				i++
+			case strings.HasPrefix(s[i:], "/*"):
+				state = protoStateBlockComment
+				i++
+			case s[i] == '[' || s[i] == '(':
+				depth++
+			case (s[i] == ']' || s[i] == ')') && depth > 0:
+				depth--
+			}
+		case protoStateString:
+			switch s[i] {
+			case '\\':
+				i++
+			case quote:
+				state = protoStateBase
+			}
+		case protoStateLineComment:
+			if s[i] == '\n' {
+				state = protoStateBase
+			}
+		case protoStateBlockComment:
+			if strings.HasPrefix(s[i:], "*/") {
+				state = protoStateBase
+				i++
+			}
+		}
+
+		if i >= len(s) || s[i] != '\n' {
+			continue
+		}
+		if state != protoStateBase || depth > 0 {
+			lineNumber++
+			continue
+		}
+
+		raw := s[start:i]
+		value := raw
+		if !comment {
+			value = joinProtoLines(raw)
+		}
+		prev.next = &sourceLine{value, raw, number, nil, nil}
+		prev = prev.next
+		start = i + 1
+		comment = false
+		lineNumber++
+		number = lineNumber
+	}
+
+	raw := s[start:]
+	prev.next = &sourceLine{joinProtoLines(raw), raw, number, nil, nil}
+
+	return zeroLine.next
+}
+
+// joinProtoLines joins lines of the statement, the indentation of the first line is kept.
+func joinProtoLines(raw string) string {
+	lines := strings.Split(raw, "\n")
+	if len(lines) == 1 {
+		return raw
+	}
+
+	parts := []string{strings.TrimRight(lines[0], " \t")}
+	for _, line := range lines[1:] {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+
+	value := strings.Join(parts, " ")
+	for _, r := range [][2]string{{"[ ", "["}, {" ]", "]"}, {"( ", "("}, {" )", ")"}} {
+		value = strings.ReplaceAll(value, r[0], r[1])
+	}
+
+	return value
+}
+
+// isProtoNumber reports whether the insert is the number of a field of the protobuf message,
+// that is the insert after "=" in a field declaration "type name = N", not in an option or field options.
+func isProtoNumber(ins *template.Insert) bool {
+	var items []template.Node
+	switch parent := ins.Parent().(type) {
+	case *template.Template:
+		items = parent.Items
+	case *template.File:
+		items = parent.Content
+	default:
+		return false
+	}
+
+	for node := ins.Parent(); node != nil; node = node.Parent() {
+		if file, ok := node.(*template.File); ok {
+			if file.Type != template.FileProto {
+				return false
+			}
+			break
+		}
+	}
+
+	idx := slices.Index(items, template.Node(ins))
+	start := idx
+	for start > 0 {
+		if _, ok := items[start-1].(*template.LineFeed); ok {
+			break
+		}
+		start--
+	}
+
+	m := protoFieldRe.FindStringSubmatch(tplText(items[start:idx]))
+	return m != nil && m[1] != "option"
+}
+
+// validProtoNumbers reports whether the parsed field numbers are numbers of field declarations,
+// otherwise the line is an option or has field options or comments which the template doesn't have.
+func validProtoNumbers(nodes []Stringer) bool {
+	for i, node := range nodes {
+		if joined, ok := node.(*Joined); ok {
+			return validProtoNumbers(joined.Items)
+		}
+		ins, ok := node.(*Insert)
+		if !ok || !isProtoNumber(ins.Template) {
+			continue
+		}
+		if _, err := strconv.Atoi(ins.Value); err != nil {
+			return false
+		}
+		if m := protoFieldRe.FindStringSubmatch(concat(nodes[:i])); m == nil || m[1] == "option" {
+			return false
+		}
+	}
+	return true
+}
+
+// nextProtoNumber returns the number following the numbers of fields and reserved numbers
+// of the message which contains the insert.
+func nextProtoNumber(ins *Insert) string {
+	var entry Stringer
+	var parent Node
+	for node := Node(ins); node != nil; node = node.GetParent() {
+		if tpl, ok := node.(*Template); ok && tpl.Template.Entry {
+			entry, parent = tpl, tpl.Parent
+			break
+		}
+	}
+
+	var items []Stringer
+	switch parent := parent.(type) {
+	case *Template:
+		items = parent.Items
+	case *File:
+		items = parent.Content
+	}
+
+	idx := slices.Index(items, entry)
+	if idx == -1 {
+		return "1"
+	}
+
+	last := 0
+	for _, n := range protoNumbers(messageBody(concat(items[:idx]), concat(items[idx+1:]))) {
+		last = max(last, n)
+	}
+
+	next := last + 1
+	if next >= protoReservedMin && next <= protoReservedMax {
+		next = protoReservedMax + 1
+	}
+
+	return strconv.Itoa(next)
+}
+
+// messageBody returns the text between the braces enclosing the position between before and after.
+func messageBody(before, after string) string {
+	depth := 0
+	open := -1
+	for i := len(before) - 1; i >= 0 && open == -1; i-- {
+		switch before[i] {
+		case '}':
+			depth++
+		case '{':
+			if depth == 0 {
+				open = i
+			}
+			depth--
+		}
+	}
+
+	depth = 0
+	end := len(after)
+	for i := 0; i < len(after) && end == len(after); i++ {
+		switch after[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				end = i
+			}
+			depth--
+		}
+	}
+
+	return before[open+1:] + "\n" + after[:end]
+}
+
+// InitialValue returns the value of the created insert the node has no value for: the next field number
+// of the protobuf message, so the number is stored in the node and never changes once assigned, otherwise empty.
+func InitialValue(ins *Insert) string {
+	if isProtoNumber(ins.Template) {
+		return nextProtoNumber(ins)
+	}
+	return ""
+}
+
+func protoNumbers(body string) []int {
+	body = protoCommentRe.ReplaceAllString(body, "")
+
+	var out []int
+	statements := strings.FieldsFunc(body, func(r rune) bool { return r == ';' || r == '{' || r == '}' })
+	for _, statement := range statements {
+		m := protoFieldNumberRe.FindStringSubmatch(statement)
+		if m == nil || m[1] == "option" {
+			continue
+		}
+		if n, err := strconv.Atoi(m[2]); err == nil {
+			out = append(out, n)
+		}
+	}
+
+	for _, m := range protoReservedRe.FindAllStringSubmatch(body, -1) {
+		for _, part := range strings.Split(m[1], ",") {
+			fields := strings.Fields(part)
+			if len(fields) == 0 {
+				continue
+			}
+			if n, err := strconv.Atoi(fields[len(fields)-1]); err == nil {
+				out = append(out, n)
+			}
+		}
+	}
+
+	return out
+}
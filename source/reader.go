@@ -48,6 +48,11 @@ func readPath(node Fs, path []template.Fs) ([]Node, error) {
 			return nil, err
 		}
 
+		if template.IsEntry(node.Template) {
+			out = append(out, node) // the entry binds its content to the child node
+			break
+		}
+
 		for _, item := range node.Content {
 			out = append(out, item)
 		}
@@ -93,7 +98,7 @@ func readFile(file *File) error {
 		return err
 	}
 
-	if err := parseContent(content, file); err != nil {
+	if err := parseContent(content, file, nil); err != nil {
 		return err
 	}
 
@@ -102,6 +107,9 @@ func readFile(file *File) error {
 	return nil
 }
 
+// LockFile is written to the root of the project by maker, it is not a part of the templated tree.
+const LockFile = ".maker.lock"
+
 func readDir(dir *Dir) error {
 	realPath, err := buildRealPath(dir)
 	if err != nil {
@@ -114,6 +122,10 @@ func readDir(dir *Dir) error {
 	}
 
 	for _, entry := range entries {
+		if dir.Parent == nil && entry.Name() == LockFile {
+			continue
+		}
+
 		var item Fs
 		if entry.IsDir() {
 			item = &Dir{nil, dir, nil, nil, entry.Name(), FsStatusNotRead}
@@ -0,0 +1,109 @@
+package source
+
+import (
+	"fmt"
+	"github.com/vologzhan/maker/template"
+	"strings"
+)
+
+type ReportKind int
+
+const (
+	ReportUnmatchedFile ReportKind = iota // file or directory in the templated directory that matches no template
+	ReportRawLine                         // line in the templated file that is kept as is
+)
+
+type ReportItem struct {
+	Kind  ReportKind
+	Path  string
+	Line  int // set for ReportRawLine only
+	Value string
+}
+
+func (i ReportItem) String() string {
+	switch i.Kind {
+	case ReportUnmatchedFile:
+		return fmt.Sprintf("%s: does not match any template", i.Path)
+	case ReportRawLine:
+		return fmt.Sprintf("%s:%d: does not match the template: %s", i.Path, i.Line, i.Value)
+	default:
+		return fmt.Sprintf("%s: unknown report kind %d", i.Path, i.Kind)
+	}
+}
+
+// Report returns content that was read, but matched no template: files and directories left
+// in the templated directories and lines of the templated files kept as raw words.
+// Nodes that were not read are skipped.
+func Report(node Fs) ([]ReportItem, error) {
+	switch n := node.(type) {
+	case *Dir:
+		if n.Status == FsStatusNotRead || n.Status == FsStatusNotExist || n.Status == FsStatusDeleted {
+			return nil, nil
+		}
+
+		var out []ReportItem
+		for _, item := range n.Items {
+			if item.GetTemplate() == nil {
+				itemPath, err := buildRealPath(item)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, ReportItem{ReportUnmatchedFile, itemPath, 0, ""})
+				continue
+			}
+
+			items, err := Report(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, items...)
+		}
+		return out, nil
+	case *File:
+		if n.Status == FsStatusNotRead || n.Status == FsStatusNotExist || n.Status == FsStatusDeleted {
+			return nil, nil
+		}
+
+		path, err := buildRealPath(n)
+		if err != nil {
+			return nil, err
+		}
+
+		line, lineStart := 1, true
+		return reportRawLines(path, n.Content, &line, &lineStart), nil
+	default:
+		return nil, fmt.Errorf("source: Report: unexpected node type '%T'", node)
+	}
+}
+
+func reportRawLines(path string, nodes []Stringer, line *int, lineStart *bool) []ReportItem {
+	var out []ReportItem
+
+	for _, node := range nodes {
+		if tpl, ok := node.(*Template); ok && tpl.Template.Entry {
+			out = append(out, reportRawLines(path, tpl.Items, line, lineStart)...)
+			if !tpl.Template.Block && !template.EndsWithEntry(tpl.Template) {
+				*line++ // the line feed is added by Template.String
+				*lineStart = true
+			}
+			continue
+		}
+
+		value := node.String()
+		if w, ok := node.(*Word); ok && w.Template == nil && w.Parent != nil && *lineStart {
+			for i, raw := range strings.Split(value, "\n") {
+				if raw != "" {
+					out = append(out, ReportItem{ReportRawLine, path, *line + i, raw})
+				}
+			}
+		}
+
+		if value == "" {
+			continue
+		}
+		*line += strings.Count(value, "\n")
+		*lineStart = strings.HasSuffix(value, "\n")
+	}
+
+	return out
+}
@@ -0,0 +1,203 @@
+package source
+
+import (
+	"github.com/vologzhan/maker/template"
+	"strings"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlTokenLineFeed sqlTokenKind = iota
+	sqlTokenSpace
+	sqlTokenWord        // keyword, identifier, number or parameter ("$1")
+	sqlTokenString      // '...', E'...' with backslash escapes
+	sqlTokenIdentifier  // "..."
+	sqlTokenComment     // -- ... or /* ... */, block comments may be nested
+	sqlTokenDollarQuote // $$...$$ or $tag$...$tag$
+	sqlTokenSymbol      // operator, bracket, comma or semicolon
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+}
+
+// splitSqlToLines splits SQL source to lines by the line feeds between tokens: a line break inside a string,
+// a quoted identifier, a block comment or a dollar-quoted body doesn't end the line,
+// so multi-line literals and function bodies are matched as a whole.
+func splitSqlToLines(content []byte) *sourceLine {
+	zeroLine := &sourceLine{}
+	prev := zeroLine
+
+	var buf strings.Builder
+	number, lineNumber := 1, 1
+	for _, tok := range sqlTokens(string(content)) {
+		if tok.kind != sqlTokenLineFeed {
+			buf.WriteString(tok.text)
+			lineNumber += strings.Count(tok.text, "\n")
+			continue
+		}
+
+		value := buf.String()
+		prev.next = &sourceLine{value, value, number, nil, nil}
+		prev = prev.next
+		buf.Reset()
+		lineNumber++
+		number = lineNumber
+	}
+
+	value := buf.String()
+	prev.next = &sourceLine{value, value, number, nil, nil}
+
+	return zeroLine.next
+}
+
+// sqlTokens splits SQL source to tokens, the text of the tokens joined is the source.
+// An unclosed string, identifier, comment or dollar-quoted body takes the rest of the source.
+func sqlTokens(s string) []sqlToken {
+	var out []sqlToken
+	for i := 0; i < len(s); {
+		kind, end := sqlTokenAt(s, i)
+		out = append(out, sqlToken{kind, s[i:end]})
+		i = end
+	}
+	return out
+}
+
+// sqlTokenAt returns the kind and the end of the token starting at i.
+func sqlTokenAt(s string, i int) (sqlTokenKind, int) {
+	switch c := s[i]; {
+	case c == '\n':
+		return sqlTokenLineFeed, i + 1
+	case c == ' ' || c == '\t' || c == '\r':
+		end := i + 1
+		for end < len(s) && (s[end] == ' ' || s[end] == '\t' || s[end] == '\r') {
+			end++
+		}
+		return sqlTokenSpace, end
+	case strings.HasPrefix(s[i:], "--"):
+		if end := strings.IndexByte(s[i:], '\n'); end != -1 {
+			return sqlTokenComment, i + end
+		}
+		return sqlTokenComment, len(s)
+	case strings.HasPrefix(s[i:], "/*"):
+		return sqlTokenComment, sqlBlockCommentEnd(s, i)
+	case c == '\'':
+		return sqlTokenString, sqlQuotedEnd(s, i, '\'', false)
+	case (c == 'E' || c == 'e') && strings.HasPrefix(s[i+1:], "'"):
+		return sqlTokenString, sqlQuotedEnd(s, i+1, '\'', true)
+	case c == '"':
+		return sqlTokenIdentifier, sqlQuotedEnd(s, i, '"', false)
+	case c == '$':
+		if tag, ok := sqlDollarTag(s[i:]); ok {
+			if end := strings.Index(s[i+len(tag):], tag); end != -1 {
+				return sqlTokenDollarQuote, i + len(tag) + end + len(tag)
+			}
+			return sqlTokenDollarQuote, len(s)
+		}
+		end := i + 1
+		for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+			end++
+		}
+		return sqlTokenWord, end
+	case isSqlWordByte(c):
+		end := i + 1
+		for end < len(s) && (isSqlWordByte(s[end]) || s[end] == '$') {
+			end++ // "$" inside a word doesn't start a dollar-quoted body
+		}
+		return sqlTokenWord, end
+	default:
+		return sqlTokenSymbol, i + 1
+	}
+}
+
+// sqlQuotedEnd returns the end of the string or identifier opened by the quote at i, the doubled quote is escaped,
+// backslash escapes the next byte in E-strings.
+func sqlQuotedEnd(s string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case backslash && s[j] == '\\':
+			j++
+		case s[j] == quote && j+1 < len(s) && s[j+1] == quote:
+			j++
+		case s[j] == quote:
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// sqlBlockCommentEnd returns the end of the block comment starting at i, nested comments are closed in order.
+func sqlBlockCommentEnd(s string, i int) int {
+	depth := 0
+	for j := i; j < len(s)-1; j++ {
+		switch {
+		case s[j] == '/' && s[j+1] == '*':
+			depth++
+			j++
+		case s[j] == '*' && s[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+func isSqlWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// sqlDollarTag returns the opening tag of a dollar-quoted string, e.g. "$$" or "$body$".
+func sqlDollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return s[:i+1], true
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80 || i > 1 && c >= '0' && c <= '9':
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// wholeBodyInsert returns the insert if the template is a single insert, e.g. "▶⬇Sql➡Up◀",
+// then the whole body of the file is its value.
+func wholeBodyInsert(content []template.Node) *template.Insert {
+	var out *template.Insert
+	for _, node := range content {
+		switch n := node.(type) {
+		case *template.Insert:
+			if out != nil || len(n.Items) > 0 {
+				return nil
+			}
+			out = n
+		case *template.LineFeed:
+		default:
+			return nil
+		}
+	}
+	return out
+}
+
+func parseWholeBody(content []byte, tpl *template.Insert, file *File) {
+	body := string(content)
+
+	var lineFeeds []Stringer
+	for i := len(file.Template.Content) - 1; i >= 0; i-- {
+		lf, ok := file.Template.Content[i].(*template.LineFeed)
+		if !ok {
+			break
+		}
+		for j := 0; j < lf.Value && strings.HasSuffix(body, "\n"); j++ {
+			body = strings.TrimSuffix(body, "\n")
+			lineFeeds = append(lineFeeds, &LineFeed{lf, file, 1})
+		}
+	}
+
+	file.Content = append([]Stringer{&Insert{tpl, file, body, nil}}, lineFeeds...)
+}
@@ -2,6 +2,8 @@ package source
 
 import (
 	"fmt"
+	"github.com/vologzhan/maker/template"
+	"slices"
 	"strings"
 )
 
@@ -16,6 +18,9 @@ func (n *LineFeed) String() string  { return strings.Repeat("\n", n.Value) }
 
 func (n *Template) String() string {
 	if n.Template.Entry {
+		if n.Template.Block || template.EndsWithEntry(n.Template) {
+			return concat(n.Items)
+		}
 		return fmt.Sprintf("%s\n", concat(n.Items))
 	}
 	if templateHasValue(n) {
@@ -54,27 +59,81 @@ func (n *Insert) String() string {
 	return n.Value
 }
 
-func (n *Imports) String() string {
-	if len(n.Items) == 0 {
-		return ""
-	}
-	if len(n.Items) == 1 {
-		return fmt.Sprintf("import %s\n", n.Items[0].String())
+func (n *Imports) String() string { return n.style.imports(n) }
+
+// importsStyle writes the imports of the file type, see golang.go and typescript.go.
+type importsStyle interface {
+	imports(n *Imports) string
+	importSpec(n *Import) string
+}
+
+func newImportsStyle(typ template.FileType) importsStyle {
+	switch typ {
+	case template.FileTs:
+		return newTsImports()
+	default:
+		return goImports{}
 	}
+}
 
-	buf := strings.Builder{}
+// grouped returns imports with their groups, imports which are not read from the source are placed
+// to the last group of the same kind (standard library or not, packages or relative modules for TypeScript),
+// or to a new group: the first for the standard library and the last for others.
+func (n *Imports) grouped(isStd func(path string) bool) ([]*Import, []int) {
+	var items []*Import
+	var groups []int
+	var added []*Import
 	for _, item := range n.Items {
-		buf.WriteString(fmt.Sprintf("\t%s\n", item.String()))
+		if item.Group == 0 {
+			added = append(added, item)
+			continue
+		}
+		items = append(items, item)
+		groups = append(groups, item.Group)
 	}
 
-	return fmt.Sprintf("import (\n%s)\n", buf.String())
+	for _, item := range added {
+		std := isStd(concat(item.Name))
+
+		idx := -1
+		for i, other := range items {
+			if isStd(concat(other.Name)) == std {
+				idx = i
+			}
+		}
+
+		switch {
+		case idx != -1:
+			items = slices.Insert(items, idx+1, item)
+			groups = slices.Insert(groups, idx+1, groups[idx])
+		case len(items) == 0:
+			items = append(items, item)
+			groups = append(groups, 1)
+		case std:
+			items = slices.Insert(items, 0, item)
+			groups = slices.Insert(groups, 0, groups[0]-1)
+		default:
+			items = append(items, item)
+			groups = append(groups, groups[len(groups)-1]+1)
+		}
+	}
+
+	return items, groups
+}
+
+// isStdImport reports whether the import is of the standard library, the first element of its path has no dot.
+func isStdImport(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
 }
 
-func (n *Import) String() string {
-	if len(n.Alias) > 0 {
-		return fmt.Sprintf("%s \"%s\"", concat(n.Alias), concat(n.Name))
+func (n *Import) String() string { return n.Parent.style.importSpec(n) }
+
+func (n *Joined) String() string {
+	if line := concat(n.Items); line != n.Value {
+		return line
 	}
-	return fmt.Sprintf("\"%s\"", concat(n.Name))
+	return n.Raw
 }
 
 func concat(nodes []Stringer) string {
@@ -0,0 +1,381 @@
+package source
+
+import (
+	"github.com/vologzhan/maker/template"
+	"slices"
+	"strings"
+)
+
+// structTpl is a line of the structured template (YAML, dotenv, JSON, Markdown, protobuf), lines are nested by indentation,
+// Markdown lines are nested by headings.
+// A line starting with a closing bracket belongs to the opening line with the same indentation.
+// A child template is represented by its first line, the rest of its lines are its children.
+type structTpl struct {
+	nodes    []template.Node
+	entry    *template.Template
+	indent   int
+	children []*structTpl
+}
+
+type structSrc struct {
+	line     *sourceLine
+	indent   int
+	children []*structSrc
+}
+
+type structMatcher struct {
+	optionalComma bool // the trailing comma is optional, it is fixed on write
+	protoNumbers  bool // field numbers of protobuf messages must be numbers
+	envKeys       bool // a line matches the template line with the same key if it matches no template line as a whole
+	yamlValues    bool // nested lines of multi-line YAML values are kept as is
+}
+
+// parseStructured matches the source against the template by the path of nested lines instead of the order of lines,
+// so siblings may be reordered. Child templates are matched repeatedly, lines that match no template are kept as is.
+func parseStructured(content []byte, file *File, exp explanation) {
+	if file.Template.Type == template.FileJson {
+		content = indentJson(content)
+	}
+
+	srcLine := splitFileToLines(content, file.Template)
+	exp.attach(srcLine)
+
+	tpls := buildStructTpl(splitStructLines(file.Template.Content), structIndent(file.Template.Type))
+	srcs := buildStructSrc(srcLine, structIndent(file.Template.Type))
+
+	m := structMatcher{
+		file.Template.Type == template.FileJson,
+		file.Template.Type == template.FileProto,
+		file.Template.Type == template.FileEnv,
+		file.Template.Type == template.FileYaml,
+	}
+	file.Content = m.match(srcs, tpls, file)
+}
+
+func (m structMatcher) match(srcs []*structSrc, tpls []*structTpl, parent Node) []Stringer {
+	var out []Stringer
+	used := make(map[*structTpl]bool)
+
+	for _, src := range srcs {
+		if strings.TrimSpace(src.line.value) == "" {
+			out = append(out, &LineFeed{nil, parent, 1})
+			explainLine(src.line, "empty line")
+			continue
+		}
+
+		nodes, matched := m.matchTemplates(src, tpls, used, parent, m.parseLine)
+		if !matched && m.envKeys {
+			nodes, matched = m.matchTemplates(src, tpls, used, parent, parseEnvKey)
+		}
+		if !matched {
+			explainLine(src.line, "kept as is, matches no template line at this path")
+			nodes = rawStructSrc(src, parent)
+		}
+		out = append(out, nodes...)
+	}
+
+	return out
+}
+
+// matchTemplates matches the source line and its children against the first matching template line.
+func (m structMatcher) matchTemplates(
+	src *structSrc,
+	tpls []*structTpl,
+	used map[*structTpl]bool,
+	parent Node,
+	parse func(*sourceLine, []template.Node, Node) ([]Stringer, bool),
+) ([]Stringer, bool) {
+	for _, tpl := range orderStructTpl(tpls) {
+		if tpl.entry == nil {
+			if used[tpl] {
+				continue
+			}
+
+			nodes, ok := parse(src.line, tpl.nodes, parent)
+			if !ok {
+				continue
+			}
+
+			used[tpl] = true
+			out := append(nodes, &LineFeed{nil, parent, 1})
+			return append(out, m.matchChildren(src, tpl.children, parent)...), true
+		}
+
+		entry := &Template{tpl.entry, parent, nil}
+		nodes, ok := parse(src.line, tpl.nodes, entry)
+		if !ok {
+			continue
+		}
+
+		entry.Items = append(nodes, &LineFeed{nil, entry, 1})
+		entry.Items = append(entry.Items, m.matchChildren(src, tpl.children, entry)...)
+		if !tpl.entry.Block && !template.EndsWithEntry(tpl.entry) {
+			if _, ok := entry.Items[len(entry.Items)-1].(*LineFeed); ok {
+				entry.Items = entry.Items[:len(entry.Items)-1] // the line feed is added by Template.String
+			}
+		}
+
+		return []Stringer{entry}, true
+	}
+
+	return nil, false
+}
+
+func (m structMatcher) matchChildren(src *structSrc, tpls []*structTpl, parent Node) []Stringer {
+	if m.yamlValues && isYamlMultiLineValue(src.line.value) {
+		return rawStructChildren(src, parent)
+	}
+	return m.match(src.children, tpls, parent)
+}
+
+// parseEnvKey matches the line by the part of the template line up to "=", the value is kept as is,
+// so a changed static value of the template variable doesn't make the line unmatched.
+func parseEnvKey(srcLine *sourceLine, nodes []template.Node, parent Node) ([]Stringer, bool) {
+	var key *template.Word
+	var keyNodes []template.Node
+	for i, node := range nodes {
+		word, ok := node.(*template.Word)
+		if !ok {
+			continue
+		}
+		if idx := strings.Index(word.Value, "="); idx != -1 {
+			key = &template.Word{Value: word.Value[:idx+1]}
+			keyNodes = append(slices.Clone(nodes[:i]), key)
+			break
+		}
+	}
+	if key == nil {
+		return nil, false
+	}
+
+	out, matched, err := parseLine(srcLine, keyNodes, parent)
+	if err != nil || !matched {
+		return nil, false
+	}
+
+	for _, node := range out {
+		if word, ok := node.(*Word); ok && word.Parent == nil {
+			word.Parent = parent // the value
+		}
+	}
+
+	return out, true
+}
+
+func (m structMatcher) parseLine(srcLine *sourceLine, nodes []template.Node, parent Node) ([]Stringer, bool) {
+	out, matched, err := parseLine(srcLine, nodes, parent)
+	if err == nil && matched && m.protoNumbers && !validProtoNumbers(out) {
+		return nil, false
+	}
+	if err == nil && matched || !m.optionalComma {
+		return out, err == nil && matched
+	}
+
+	value := srcLine.value + ","
+	if strings.HasSuffix(srcLine.value, ",") {
+		value = strings.TrimSuffix(srcLine.value, ",")
+	}
+
+	out, matched, err = parseLine(&sourceLine{value, value, srcLine.number, nil, srcLine.explain}, nodes, parent)
+	return out, err == nil && matched
+}
+
+// orderStructTpl returns plain lines before child templates, plain lines are more specific.
+func orderStructTpl(tpls []*structTpl) []*structTpl {
+	var head, tail []*structTpl
+	for _, tpl := range tpls {
+		if tpl.entry == nil {
+			head = append(head, tpl)
+		} else {
+			tail = append(tail, tpl)
+		}
+	}
+	return append(head, tail...)
+}
+
+func rawStructSrc(src *structSrc, parent Node) []Stringer {
+	return append([]Stringer{&Word{nil, parent, src.line.raw}, &LineFeed{nil, parent, 1}}, rawStructChildren(src, parent)...)
+}
+
+func rawStructChildren(src *structSrc, parent Node) []Stringer {
+	var out []Stringer
+	for _, child := range src.children {
+		if strings.TrimSpace(child.line.value) == "" {
+			out = append(out, &LineFeed{nil, parent, 1})
+			continue
+		}
+		out = append(out, rawStructSrc(child, parent)...)
+	}
+	return out
+}
+
+func buildStructSrc(first *sourceLine, indent func(string) int) []*structSrc {
+	var lines []*structSrc
+	for line := first; line != nil; line = line.next {
+		if line.next == nil && line.value == "" {
+			break // the end of the last line
+		}
+		lines = append(lines, &structSrc{line, indent(line.value), nil})
+	}
+
+	// empty lines belong to the parent of the next line
+	next := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(lines[i].line.value) == "" {
+			lines[i].indent = next
+		} else {
+			next = lines[i].indent
+		}
+	}
+
+	root := &structSrc{indent: -1}
+	stack := []*structSrc{root}
+	for _, line := range lines {
+		for stack[len(stack)-1].indent > line.indent {
+			stack = stack[:len(stack)-1]
+		}
+		if top := stack[len(stack)-1]; top != root && top.indent == line.indent && isClosingLine(line.line.value) {
+			top.children = append(top.children, line) // the closing bracket belongs to the opening line
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		for stack[len(stack)-1].indent >= line.indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, line)
+		if strings.TrimSpace(line.line.value) != "" {
+			stack = append(stack, line)
+		}
+	}
+
+	return root.children
+}
+
+func buildStructTpl(lines []blockLine, indent func(string) int) []*structTpl {
+	root := &structTpl{indent: -1}
+	stack := []*structTpl{root}
+
+	for _, line := range lines {
+		var tpl *structTpl
+		if line.entry != nil {
+			entryLines := splitStructLines(line.entry.Items)
+			head := 0
+			for head < len(entryLines) && len(entryLines[head].nodes) == 0 && entryLines[head].entry == nil {
+				head++
+			}
+			if head == len(entryLines) || entryLines[head].entry != nil {
+				continue // child template without the first line can't be anchored
+			}
+
+			tpl = &structTpl{entryLines[head].nodes, line.entry, indent(tplText(entryLines[head].nodes)), nil}
+			tpl.children = buildStructTpl(entryLines[head+1:], indent)
+		} else {
+			if len(line.nodes) == 0 {
+				continue
+			}
+			tpl = &structTpl{line.nodes, nil, indent(tplText(line.nodes)), nil}
+		}
+
+		for stack[len(stack)-1].indent > tpl.indent {
+			stack = stack[:len(stack)-1]
+		}
+		if top := stack[len(stack)-1]; top != root && top.indent == tpl.indent && tpl.entry == nil && isClosingTpl(tpl.nodes) {
+			top.children = append(top.children, tpl)
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		for stack[len(stack)-1].indent >= tpl.indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, tpl)
+		stack = append(stack, tpl)
+	}
+
+	return root.children
+}
+
+// splitStructLines splits template nodes to lines, child templates take their own lines.
+func splitStructLines(nodes []template.Node) []blockLine {
+	var out []blockLine
+	var buf []template.Node
+
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *template.LineFeed:
+			out = append(out, blockLine{buf, nil})
+			for i := 1; i < n.Value; i++ {
+				out = append(out, blockLine{})
+			}
+			buf = nil
+			continue
+		case *template.Template:
+			if n.Entry {
+				if len(buf) > 0 {
+					out = append(out, blockLine{buf, nil})
+					buf = nil
+				}
+				out = append(out, blockLine{nil, n})
+				continue
+			}
+		}
+
+		buf = append(buf, node)
+	}
+
+	if len(buf) > 0 {
+		out = append(out, blockLine{buf, nil})
+	}
+
+	return out
+}
+
+func isClosingLine(line string) bool {
+	line = strings.TrimSpace(line)
+	return line != "" && strings.ContainsAny(line[:1], "}]")
+}
+
+func isClosingTpl(nodes []template.Node) bool {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *template.Separator:
+			continue
+		case *template.Word:
+			return isClosingLine(n.Value)
+		}
+		return false
+	}
+	return false
+}
+
+// structIndent returns the function of nesting levels of lines of the file, the function is called in order of lines.
+func structIndent(typ template.FileType) func(string) int {
+	switch typ {
+	case template.FileMarkdown:
+		return markdownIndent()
+	case template.FileYaml:
+		return yamlIndent
+	}
+	return indentOf
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// tplText returns the text of the template line, inserts are replaced with a placeholder.
+func tplText(nodes []template.Node) string {
+	var sb strings.Builder
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *template.Separator:
+			sb.WriteString(n.Value)
+		case *template.Word:
+			sb.WriteString(n.Value)
+		default:
+			sb.WriteString("_")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,442 @@
+package source
+
+import (
+	"fmt"
+	"github.com/vologzhan/maker/template"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type tsState int
+
+const (
+	tsStateBase tsState = iota
+	tsStateString
+	tsStateTemplateLiteral
+	tsStateLineComment
+	tsStateBlockComment
+	tsStateRegexp
+)
+
+var (
+	tsImportRe = regexp.MustCompile(`^import\s+(?:(.+?)\s+from\s+)?(['"])([^'"]*)['"]\s*;?\s*(//.*)?$`)
+	tsClauseRe = regexp.MustCompile(`^(type\s+)?(?:([\w$]+)\s*,?\s*)?(?:\{([^}]*)\})?$`)
+)
+
+// splitTsToLines splits TypeScript and JavaScript source to lines with the tokenizer, a line break inside a string,
+// a template literal, a block comment, parentheses, brackets or braces of an import doesn't end the line.
+// Brackets and quotes inside regexp literals are skipped.
+// Lines of a statement are joined to one line with single spaces, e.g. arguments of a call or a multi-line import.
+func splitTsToLines(content []byte) *sourceLine {
+	s := string(content)
+	zeroLine := &sourceLine{}
+	prev := zeroLine
+
+	state := tsStateBase
+	var quote byte
+	class := false // inside a character class of the regexp literal
+	depth := 0
+	comment := false // a line comment inside the statement, lines can't be joined
+	start, number, lineNumber := 0, 1, 1
+	imp := isTsImport(s)
+
+	for i := 0; i < len(s); i++ {
+		switch state {
+		case tsStateBase:
+			switch {
+			case s[i] == '"' || s[i] == '\'':
+				state = tsStateString
+				quote = s[i]
+			case s[i] == '`':
+				state = tsStateTemplateLiteral
+			case strings.HasPrefix(s[i:], "//"):
+				state = tsStateLineComment
+				comment = comment || depth > 0
+				i++
+			case strings.HasPrefix(s[i:], "/*"):
+				state = tsStateBlockComment
+				i++
+			case s[i] == '/' && tsRegexpAllowed(s[start:i]):
+				state = tsStateRegexp
+				class = false
+			case s[i] == '(' || s[i] == '[' || imp && s[i] == '{':
+				depth++
+			case (s[i] == ')' || s[i] == ']' || imp && s[i] == '}') && depth > 0:
+				depth--
+			}
+		case tsStateString:
+			switch s[i] {
+			case '\\':
+				i++
+			case quote, '\n':
+				state = tsStateBase
+			}
+		case tsStateTemplateLiteral:
+			switch s[i] {
+			case '\\':
+				i++
+			case '`':
+				state = tsStateBase
+			}
+		case tsStateLineComment:
+			if s[i] == '\n' {
+				state = tsStateBase
+			}
+		case tsStateBlockComment:
+			if strings.HasPrefix(s[i:], "*/") {
+				state = tsStateBase
+				i++
+			}
+		case tsStateRegexp:
+			switch s[i] {
+			case '\\':
+				i++
+			case '[':
+				class = true
+			case ']':
+				class = false
+			case '/':
+				if !class {
+					state = tsStateBase
+				}
+			case '\n':
+				state = tsStateBase // not a regexp literal, it can't span lines
+			}
+		}
+
+		if i >= len(s) || s[i] != '\n' {
+			continue
+		}
+		if state != tsStateBase || depth > 0 {
+			lineNumber++
+			continue
+		}
+
+		raw := s[start:i]
+		value := raw
+		if !comment {
+			value = joinTsLines(raw)
+		}
+		prev.next = &sourceLine{value, raw, number, nil, nil}
+		prev = prev.next
+		start = i + 1
+		comment = false
+		imp = isTsImport(s[start:])
+		lineNumber++
+		number = lineNumber
+	}
+
+	raw := s[start:]
+	prev.next = &sourceLine{joinTsLines(raw), raw, number, nil, nil}
+
+	return zeroLine.next
+}
+
+// tsRegexpAllowed reports whether "/" after the code starts a regexp literal instead of being the division,
+// that is it follows an operator, an opening bracket, a keyword or starts the statement.
+func tsRegexpAllowed(before string) bool {
+	before = strings.TrimRight(before, " \t\n")
+	if before == "" {
+		return true
+	}
+	if strings.ContainsAny(before[len(before)-1:], "(,=:[!&|?{};+-*%<>~^") {
+		return true
+	}
+
+	word := before[strings.LastIndexFunc(before, func(r rune) bool {
+		return !(r == '_' || r == '$' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})+1:]
+	return slices.Contains([]string{"return", "typeof", "case", "do", "else", "in", "of", "new", "delete", "void", "throw", "yield", "await"}, word)
+}
+
+func isTsImport(s string) bool {
+	s = strings.TrimLeft(s, " \t")
+	return strings.HasPrefix(s, "import ") || strings.HasPrefix(s, "import{") || strings.HasPrefix(s, "export {") || strings.HasPrefix(s, "export type {")
+}
+
+// tsImportLines returns the import declarations starting from the line with comments and empty lines between them.
+func tsImportLines(first *sourceLine) []*sourceLine {
+	var out, buf []*sourceLine
+	for line := first; line != nil; line = line.next {
+		value := strings.TrimSpace(line.value)
+		switch {
+		case value == "" || strings.HasPrefix(value, "//") || strings.HasPrefix(value, "/*"):
+			buf = append(buf, line)
+		case tsImportRe.MatchString(value):
+			out = append(append(out, buf...), line)
+			buf = nil
+		default:
+			return out
+		}
+	}
+	return out
+}
+
+// readTsImports reads the import declarations starting from the line,
+// returns the last line of the declarations or nil if the line doesn't start them.
+func readTsImports(first *sourceLine, tpl *template.Imports, f *File) (*sourceLine, error) {
+	lines := tsImportLines(first)
+	if err := parseTsImports(lines, tpl, f); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	return lines[len(lines)-1], nil
+}
+
+// parseTsImports reads the import declarations, the module is matched against the name of the template import
+// and the import clause against its alias. Imports are grouped by empty lines, comments above are kept as their docs.
+func parseTsImports(lines []*sourceLine, tpl *template.Imports, f *File) error {
+	style := newTsImports()
+	srcImps := &Imports{tpl, f, nil, nil, style}
+	f.Content = append(f.Content, srcImps)
+
+	tplImps := getSortedTplImports(tpl)
+
+	group := 1
+	blank := false
+	quotes := make(map[byte]int)
+	var doc []string
+	for _, line := range lines {
+		explainLine(line, "imports")
+
+		value := strings.TrimSpace(line.value)
+		if value == "" {
+			blank = len(srcImps.Items) > 0
+			continue
+		}
+		if strings.HasPrefix(value, "//") || strings.HasPrefix(value, "/*") {
+			doc = append(doc, line.raw)
+			continue
+		}
+		if blank {
+			group++
+			blank = false
+		}
+
+		m := tsImportRe.FindStringSubmatch(value)
+		clause, quote, module := m[1], m[2][0], m[3]
+		quotes[quote]++
+		if quotes[quote] > quotes[style.quote] {
+			style.quote = quote
+		}
+
+		srcImp := &Import{nil, srcImps, nil, nil, doc, m[4], group}
+		srcImps.Items = append(srcImps.Items, srcImp)
+		doc = nil
+
+		for i, tplImp := range tplImps {
+			srcName, matched, err := parse(module, tplImp.Name, srcImp)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				continue
+			}
+
+			srcImp.Template = tplImp
+			srcImp.Name = srcName
+			if !tplImp.Entry {
+				tplImps = append(tplImps[:i], tplImps[i+1:]...)
+			}
+
+			if clause != "" {
+				srcAlias, _, err := parse(clause, tplImp.Alias, srcImp)
+				if err != nil {
+					return err
+				}
+				srcImp.Alias = srcAlias
+			}
+
+			break
+		}
+
+		if len(srcImp.Name) == 0 {
+			srcImp.Name = []Stringer{&Word{nil, srcImp, module}}
+			if clause != "" {
+				srcImp.Alias = []Stringer{&Word{nil, srcImp, clause}}
+			}
+		}
+
+		read := tsReadImport{line.raw, "", quote}
+		style.read[srcImp] = read
+		read.value = style.declaration(srcImp, nil)
+		style.read[srcImp] = read
+	}
+
+	return nil
+}
+
+// tsImports writes the import declarations of the TypeScript file. A read declaration is written with its quotes
+// and keeps its original lines while it is not changed, new declarations use the quotes most read ones use.
+type tsImports struct {
+	read  map[*Import]tsReadImport
+	quote byte
+}
+
+type tsReadImport struct {
+	raw   string // the original lines, written while the declaration renders value
+	value string
+	quote byte
+}
+
+func newTsImports() *tsImports {
+	return &tsImports{make(map[*Import]tsReadImport), '\''}
+}
+
+// imports writes the import declarations by groups, the named imports of the same module are merged
+// into the first declaration, so an import added by the template and the same import written by hand don't repeat.
+func (s *tsImports) imports(n *Imports) string {
+	items, groups := n.grouped(func(path string) bool { return !strings.HasPrefix(path, ".") })
+
+	merged := make(map[*Import][]string)
+	skipped := make(map[*Import]bool)
+	for i, item := range items {
+		clause, ok := parseTsClause(concat(item.Alias))
+		if !ok {
+			continue
+		}
+		for _, prev := range items[:i] {
+			prevClause, ok := parseTsClause(concat(prev.Alias))
+			if !ok || skipped[prev] || concat(prev.Name) != concat(item.Name) || prevClause.typeOnly != clause.typeOnly {
+				continue
+			}
+			if prevClause.def != "" && clause.def != "" && prevClause.def != clause.def {
+				continue
+			}
+
+			if clause.def != "" {
+				merged[prev] = append(merged[prev], clause.def+" default")
+			}
+			merged[prev] = append(merged[prev], clause.names...)
+			skipped[item] = true
+			break
+		}
+	}
+
+	var buf strings.Builder
+	last := -1
+	for i, item := range items {
+		if skipped[item] {
+			continue
+		}
+		if last != -1 && groups[i] != groups[last] {
+			buf.WriteString("\n")
+		}
+		last = i
+
+		for _, doc := range item.Doc {
+			buf.WriteString(doc + "\n")
+		}
+		buf.WriteString(s.declaration(item, merged[item]) + "\n")
+	}
+	for _, line := range n.Tail {
+		buf.WriteString(line + "\n")
+	}
+
+	return buf.String()
+}
+
+func (s *tsImports) importSpec(n *Import) string {
+	return s.declaration(n, nil)
+}
+
+// declaration writes the import declaration with the names merged from other declarations of the module,
+// the original lines are written while the declaration is not changed.
+func (s *tsImports) declaration(n *Import, names []string) string {
+	clause := concat(n.Alias)
+	if len(names) > 0 {
+		c, _ := parseTsClause(clause)
+		for _, name := range names {
+			if def, ok := strings.CutSuffix(name, " default"); ok {
+				c.def = def
+			} else if !slices.Contains(c.names, name) {
+				c.names = append(c.names, name)
+			}
+		}
+		clause = c.String()
+	}
+
+	read, isRead := s.read[n]
+	quote := s.quote
+	if isRead {
+		quote = read.quote
+	}
+
+	module := string(quote) + concat(n.Name) + string(quote)
+	out := fmt.Sprintf("import %s;", module)
+	if clause != "" {
+		out = fmt.Sprintf("import %s from %s;", clause, module)
+	}
+	if n.Comment != "" {
+		out += " " + n.Comment
+	}
+
+	if isRead && out == read.value {
+		return read.raw
+	}
+	return out
+}
+
+// tsClause is the import clause "type Default, { A, B as C }", namespace imports "* as ns" are not merged.
+type tsClause struct {
+	typeOnly bool
+	def      string
+	names    []string
+}
+
+func parseTsClause(clause string) (tsClause, bool) {
+	m := tsClauseRe.FindStringSubmatch(clause)
+	if m == nil || clause == "" {
+		return tsClause{}, false
+	}
+
+	c := tsClause{m[1] != "", m[2], nil}
+	for _, name := range strings.Split(m[3], ",") {
+		if name = strings.Join(strings.Fields(name), " "); name != "" {
+			c.names = append(c.names, name)
+		}
+	}
+	return c, true
+}
+
+func (c tsClause) String() string {
+	var parts []string
+	if c.def != "" {
+		parts = append(parts, c.def)
+	}
+	if len(c.names) > 0 {
+		parts = append(parts, "{ "+strings.Join(c.names, ", ")+" }")
+	}
+
+	out := strings.Join(parts, ", ")
+	if c.typeOnly {
+		out = "type " + out
+	}
+	return out
+}
+
+// joinTsLines joins lines of the statement with single spaces, the indentation of the first line is kept.
+// Trailing commas before closing brackets are dropped, so a multi-line list matches a single-line template.
+func joinTsLines(raw string) string {
+	lines := strings.Split(raw, "\n")
+	if len(lines) == 1 {
+		return raw
+	}
+
+	parts := []string{strings.TrimRight(lines[0], " \t")}
+	for _, line := range lines[1:] {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+
+	value := strings.Join(parts, " ")
+	for _, r := range [][2]string{{"[ ", "["}, {" ]", "]"}, {"( ", "("}, {" )", ")"}, {",]", "]"}, {",)", ")"}, {", }", " }"}} {
+		value = strings.ReplaceAll(value, r[0], r[1])
+	}
+
+	return value
+}
@@ -0,0 +1,53 @@
+package source
+
+import (
+	"regexp"
+	"strings"
+)
+
+// yamlBlockScalarRe matches the line starting a block scalar. YAML is read as nested lines of the block style
+// like other structured files, not as a yaml.Node tree, so lines of multi-line values are kept as is.
+var yamlBlockScalarRe = regexp.MustCompile(`(?:^\s*|:\s+|^\s*-\s+)[|>][1-9+-]{0,2}\s*(?:#.*)?$`)
+
+// isYamlMultiLineValue reports whether the value of the line continues on the nested lines:
+// a block scalar ("key: |", "- >-"), an unclosed flow collection or an unclosed quoted scalar.
+func isYamlMultiLineValue(line string) bool {
+	if yamlBlockScalarRe.MatchString(line) {
+		return true
+	}
+
+	var quote rune
+	depth := 0
+	prev := ' '
+	for _, r := range strings.TrimSpace(line) {
+		switch {
+		case quote == '"' && r == '\\' && prev == '\\':
+			r = ' ' // escaped backslash doesn't escape the next rune
+		case quote != 0:
+			if r == quote && (quote == '\'' || prev != '\\') {
+				quote = 0
+			}
+		case r == '#' && (prev == ' ' || prev == '\t'):
+			return depth > 0
+		case (r == '"' || r == '\'') && strings.ContainsRune(" \t:-[{,", prev):
+			quote = r
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		}
+		prev = r
+	}
+
+	return quote != 0 || depth > 0
+}
+
+// yamlIndent returns the indentation of the line, a sequence item is nested deeper than its key,
+// so the items of a compact sequence ("key:" followed by "- item" at the same indentation) belong to the key.
+func yamlIndent(line string) int {
+	indent := indentOf(line)
+	if rest := line[indent:]; rest == "-" || strings.HasPrefix(rest, "- ") {
+		return indent + 1
+	}
+	return indent
+}
@@ -0,0 +1,3 @@
+module ▶service-name◀
+
+▶⤵go.mod.t◀
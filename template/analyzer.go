@@ -74,6 +74,7 @@ func getOrAddChild(nspace *Namespace, name string) *Namespace {
 			nil,
 			nil,
 			make(map[string]bool),
+			nil,
 		}
 		nspace.Children[name] = child
 	}
@@ -0,0 +1,55 @@
+package template
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// OpenBundle opens the template bundle, a directory or a zip archive with the tree of templates
+// and ".maker.yaml" manifest. Bundles embedded with embed.FS are passed to New directly,
+// the directory is embedded with "all:" prefix, e.g. "//go:embed all:templates", otherwise
+// go:embed drops ".maker.yaml" and "_partials".
+func OpenBundle(path string) (fs.FS, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("template: OpenBundle: %w", err)
+	}
+	if info.IsDir() {
+		return os.DirFS(path), nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("template: OpenBundle: %w", err)
+	}
+
+	return ReadZipBundle(content)
+}
+
+// ReadZipBundle reads the zip archive with the template bundle, e.g. embedded as []byte.
+func ReadZipBundle(content []byte) (fs.FS, error) {
+	r, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("template: ReadZipBundle: %w", err)
+	}
+
+	// archives are usually made of a directory, then the manifest is in it
+	if _, err := fs.Stat(r, ConfigFile); errors.Is(err, fs.ErrNotExist) {
+		entries, err := fs.ReadDir(r, ".")
+		if err != nil {
+			return nil, fmt.Errorf("template: ReadZipBundle: %w", err)
+		}
+		if len(entries) == 1 && entries[0].IsDir() {
+			if _, err := fs.Stat(r, path.Join(entries[0].Name(), ConfigFile)); err == nil {
+				return fs.Sub(r, entries[0].Name())
+			}
+		}
+	}
+
+	return r, nil
+}
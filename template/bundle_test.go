@@ -0,0 +1,89 @@
+package template
+
+import (
+	"archive/zip"
+	"bytes"
+	"embed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+//go:embed all:_test/bundle
+var bundleAll embed.FS
+
+//go:embed _test/bundle
+var bundleWithoutAll embed.FS
+
+func TestOpenBundleDir(t *testing.T) {
+	fsys, err := OpenBundle("_test/bundle")
+	require.NoError(t, err)
+	assertBundle(t, fsys)
+}
+
+func TestOpenBundleZip(t *testing.T) {
+	zipPath := filepath.Join(t.TempDir(), "bundle.zip")
+	require.NoError(t, os.WriteFile(zipPath, mustZipBundle(t, ""), 0644))
+
+	fsys, err := OpenBundle(zipPath)
+	require.NoError(t, err)
+	assertBundle(t, fsys)
+}
+
+func TestReadZipBundle(t *testing.T) {
+	fsys, err := ReadZipBundle(mustZipBundle(t, ""))
+	require.NoError(t, err)
+	assertBundle(t, fsys)
+
+	fsys, err = ReadZipBundle(mustZipBundle(t, "bundle-v1.0.0"))
+	require.NoError(t, err)
+	assertBundle(t, fsys) // the single root directory is stripped
+}
+
+func TestEmbedBundle(t *testing.T) {
+	fsys, err := fs.Sub(bundleAll, "_test/bundle")
+	require.NoError(t, err)
+	assertBundle(t, fsys)
+
+	fsys, err = fs.Sub(bundleWithoutAll, "_test/bundle")
+	require.NoError(t, err)
+	_, err = fs.Stat(fsys, ConfigFile)
+	assert.ErrorIs(t, err, fs.ErrNotExist, "go:embed without all: drops the manifest")
+}
+
+func assertBundle(t *testing.T, fsys fs.FS) {
+	tpl, err := New(fsys, "")
+	require.NoError(t, err)
+	assert.Equal(t, "bundle", tpl.Config.Name)
+	assert.Equal(t, "v1.0.0", tpl.Config.Version)
+	assert.Equal(t, 1, len(tpl.Entrypoints[0].(*Dir).Items), "partials are not generated")
+}
+
+func mustZipBundle(t *testing.T, root string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	err := fs.WalkDir(os.DirFS("_test/bundle"), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(filepath.Join("_test/bundle", name))
+		if err != nil {
+			return err
+		}
+		f, err := w.Create(path.Join(root, name))
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(content)
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
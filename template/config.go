@@ -0,0 +1,212 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vologzhan/maker/template/lexer"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"maps"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// ConfigFile is the name of the template tree config, it is placed in the root of the tree.
+const ConfigFile = ".maker.yaml"
+
+type Config struct {
+	Name     string        `yaml:"name"`    // name of the template bundle, the project records it on flush
+	Version  string        `yaml:"version"` // semantic version of the template bundle, e.g. "v1.2.0"
+	Markers  ConfigMarkers `yaml:"markers"`
+	Partials string        `yaml:"partials"` // directory of included fragments relative to the template tree, the path in fs once read
+	// Imports maps qualified Go types to import paths, e.g. "decimal.Decimal" or "pgtype.*" for all types of the package.
+	// The import is added to the Go file when a value of an insert contains the type.
+	Imports map[string]string `yaml:"imports"`
+	Format  ConfigFormat      `yaml:"format"`
+	Hooks   ConfigHooks       `yaml:"hooks"`
+	// References between values and namespaces, they are validated on flush and on delete.
+	References []Reference `yaml:"references"`
+}
+
+// Reference declares that the value of a node is the value of another node, e.g. "attribute.fk_table" is "entity.name_db".
+type Reference struct {
+	From     string `yaml:"from"`      // namespace and value of the referencing node
+	To       string `yaml:"to"`        // namespace and value of the referenced node
+	Scope    string `yaml:"scope"`     // namespace within which the referenced node is searched, the nearest common by default
+	OnDelete string `yaml:"on_delete"` // the referenced node is deleted: "restrict" (default) refuses, "cascade" deletes referencing nodes
+}
+
+const (
+	OnDeleteRestrict = "restrict"
+	OnDeleteCascade  = "cascade"
+)
+
+// FromNamespace returns the namespace and the value of the referencing node.
+func (r Reference) FromNamespace() (string, string) {
+	nspace, value, _ := strings.Cut(r.From, ".")
+	return nspace, value
+}
+
+// ToNamespace returns the namespace and the value of the referenced node.
+func (r Reference) ToNamespace() (string, string) {
+	nspace, value, _ := strings.Cut(r.To, ".")
+	return nspace, value
+}
+
+type ConfigHooks struct {
+	PostFlush []Hook `yaml:"post_flush"` // run in order after changes are written to the project
+}
+
+// Hook is a local command, it runs in the root of the project,
+// paths of changed files are passed in the MAKER_CHANGED_FILES environment variable separated by line feeds.
+type Hook struct {
+	Name  string   `yaml:"name"` // the command is used if empty
+	Run   []string `yaml:"run"`
+	Files string   `yaml:"files"` // pattern of the file name, the hook runs only if a matching file is changed
+}
+
+// ConfigFormat is the formatting of written files, the built-in formatting of the file type goes first.
+type ConfigFormat struct {
+	LocalPrefix string          `yaml:"local_prefix"` // comma-separated prefixes, goimports groups their imports after third-party ones
+	Commands    []FormatCommand `yaml:"commands"`     // external formatters, applied in order
+}
+
+// FormatCommand reads the content of the file from stdin and writes the formatted content to stdout,
+// "{path}" in arguments is replaced with the path of the file.
+type FormatCommand struct {
+	Files string   `yaml:"files"` // pattern of the file name, e.g. "*.ts"
+	Run   []string `yaml:"run"`
+}
+
+// DefaultImports are imports of types which are known without the config.
+var DefaultImports = map[string]string{
+	"uuid.UUID":       "github.com/google/uuid",
+	"time.Time":       "time",
+	"json.RawMessage": "encoding/json",
+}
+
+type ConfigMarkers struct {
+	Path    lexer.Markers `yaml:"path"`
+	Content lexer.Markers `yaml:"content"`
+}
+
+func readConfig(fsys fs.FS, dir string) (*Config, error) {
+	// the config is decoded over the defaults, so markers missing in the config stay default
+	// and an empty string disables the marker
+	cfg := &Config{
+		Markers: ConfigMarkers{
+			Path:    lexer.DefaultPathMarkers,
+			Content: lexer.DefaultContentMarkers,
+		},
+		Partials: "_partials",
+	}
+
+	content, err := fs.ReadFile(fsys, filepath.Join(dir, ConfigFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		cfg.Imports = maps.Clone(DefaultImports)
+		cfg.Partials = filepath.Join(dir, cfg.Partials)
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(content, cfg); err != nil {
+		return nil, fmt.Errorf("template: readConfig: %w", err)
+	}
+	if err := validateMarkers("path", cfg.Markers.Path); err != nil {
+		return nil, err
+	}
+	if err := validateMarkers("content", cfg.Markers.Content); err != nil {
+		return nil, err
+	}
+	cfg.Partials = filepath.Join(dir, cfg.Partials) // partials are excluded from the tree and read by the same path
+
+	for _, cmd := range cfg.Format.Commands {
+		if len(cmd.Run) == 0 {
+			return nil, fmt.Errorf("template: readConfig: format command for '%s' is empty", cmd.Files)
+		}
+		if _, err := path.Match(cmd.Files, ""); err != nil {
+			return nil, fmt.Errorf("template: readConfig: format command files '%s': %w", cmd.Files, err)
+		}
+	}
+
+	for i, hook := range cfg.Hooks.PostFlush {
+		if len(hook.Run) == 0 {
+			return nil, fmt.Errorf("template: readConfig: hook '%s' command is empty", hook.Name)
+		}
+		if _, err := path.Match(hook.Files, ""); err != nil {
+			return nil, fmt.Errorf("template: readConfig: hook '%s' files '%s': %w", hook.Name, hook.Files, err)
+		}
+		if hook.Name == "" {
+			cfg.Hooks.PostFlush[i].Name = strings.Join(hook.Run, " ")
+		}
+	}
+
+	for _, ref := range cfg.References {
+		if !strings.Contains(ref.From, ".") || !strings.Contains(ref.To, ".") {
+			return nil, fmt.Errorf("template: readConfig: reference '%s' -> '%s': expected 'namespace.value'", ref.From, ref.To)
+		}
+		if ref.OnDelete != "" && ref.OnDelete != OnDeleteRestrict && ref.OnDelete != OnDeleteCascade {
+			return nil, fmt.Errorf("template: readConfig: reference '%s' -> '%s': unknown on_delete '%s'", ref.From, ref.To, ref.OnDelete)
+		}
+	}
+
+	for typ, path := range DefaultImports {
+		if _, ok := cfg.Imports[typ]; !ok {
+			if cfg.Imports == nil {
+				cfg.Imports = make(map[string]string)
+			}
+			cfg.Imports[typ] = path
+		}
+	}
+
+	return cfg, nil
+}
+
+// validateMarkers checks the markers merged from the config, the lexer can't read a template with
+// a missing template start or end, markers made of letters or digits, or the same marker set twice.
+func validateMarkers(kind string, m lexer.Markers) error {
+	markers := []struct{ name, value string }{
+		{"template_start", m.TemplateStart},
+		{"template_end", m.TemplateEnd},
+		{"child_start", m.TemplateChildStart},
+		{"child_end", m.TemplateChildEnd},
+		{"block_start", m.TemplateBlockStart},
+		{"block_end", m.TemplateBlockEnd},
+		{"condition", m.TemplateCondition},
+		{"separator", m.TemplateSeparator},
+		{"key", m.TemplateKey},
+		{"escape", m.Escape},
+		{"comment", m.Comment},
+		{"include", m.Include},
+	}
+
+	if m.TemplateStart == "" || m.TemplateEnd == "" {
+		return fmt.Errorf("template: readConfig: %s markers 'template_start' and 'template_end' are required", kind)
+	}
+	if (m.TemplateChildStart == "") != (m.TemplateChildEnd == "") {
+		return fmt.Errorf("template: readConfig: %s markers 'child_start' and 'child_end' are set only together", kind)
+	}
+	if (m.TemplateBlockStart == "") != (m.TemplateBlockEnd == "") {
+		return fmt.Errorf("template: readConfig: %s markers 'block_start' and 'block_end' are set only together", kind)
+	}
+
+	names := make(map[string]string)
+	for _, marker := range markers {
+		if marker.value == "" {
+			continue
+		}
+		if strings.IndexFunc(marker.value, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) != -1 {
+			return fmt.Errorf("template: readConfig: %s marker '%s' contains a letter or digit: '%s'", kind, marker.name, marker.value)
+		}
+		if name, ok := names[marker.value]; ok {
+			return fmt.Errorf("template: readConfig: %s markers '%s' and '%s' are the same: '%s'", kind, name, marker.name, marker.value)
+		}
+		names[marker.value] = marker.name
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package template
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// expandIncludes replaces includes of partials with their content, as if it were written inline.
+func expandIncludes(fsys fs.FS, content []byte, cfg *Config, stack []string) ([]byte, error) {
+	m := cfg.Markers.Content
+	if m.Include == "" || m.TemplateStart == "" || m.TemplateEnd == "" {
+		return content, nil
+	}
+
+	start := m.TemplateStart + m.Include
+	src := string(content)
+
+	var buf strings.Builder
+	for {
+		i := strings.Index(src, start)
+		if i == -1 {
+			buf.WriteString(src)
+			break
+		}
+		if m.Escape != "" && strings.HasSuffix(src[:i], m.Escape) {
+			buf.WriteString(src[:i+len(start)])
+			src = src[i+len(start):]
+			continue
+		}
+
+		j := strings.Index(src[i:], m.TemplateEnd)
+		if j == -1 {
+			return nil, fmt.Errorf("template: expandIncludes: include is not closed: '%s'", src[i:])
+		}
+
+		name := strings.TrimSpace(src[i+len(start) : i+j])
+		partial, err := readPartial(fsys, name, cfg, stack)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.WriteString(src[:i])
+		buf.Write(partial)
+		src = src[i+j+len(m.TemplateEnd):]
+	}
+
+	return []byte(buf.String()), nil
+}
+
+func readPartial(fsys fs.FS, name string, cfg *Config, stack []string) ([]byte, error) {
+	path := filepath.Join(cfg.Partials, name)
+	if slices.Contains(stack, path) {
+		return nil, fmt.Errorf("template: readPartial: cyclic include of '%s'", path)
+	}
+
+	content, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("template: readPartial: %w", err)
+	}
+
+	content, err = expandIncludes(fsys, content, cfg, append(stack, path))
+	if err != nil {
+		return nil, err
+	}
+
+	// line feed at the end of partial belongs to the line of include
+	return []byte(strings.TrimSuffix(string(content), "\n")), nil
+}
@@ -2,42 +2,101 @@ package lexer
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"unicode"
 )
 
+// Markers are the template symbols recognized by lexer, empty marker is disabled.
+type Markers struct {
+	TemplateStart      string `yaml:"template_start"`
+	TemplateEnd        string `yaml:"template_end"`
+	TemplateChildStart string `yaml:"child_start"`
+	TemplateChildEnd   string `yaml:"child_end"`
+	TemplateBlockStart string `yaml:"block_start"`
+	TemplateBlockEnd   string `yaml:"block_end"`
+	TemplateCondition  string `yaml:"condition"`
+	TemplateSeparator  string `yaml:"separator"`
+	TemplateKey        string `yaml:"key"`
+	Escape             string `yaml:"escape"`  // makes the following marker a part of word
+	Comment            string `yaml:"comment"` // turns template into comment when follows its start
+	Include            string `yaml:"include"` // turns template into include of partial, expanded before lexing
+}
+
+var DefaultPathMarkers = Markers{
+	TemplateStart: "{",
+	TemplateEnd:   "}",
+	TemplateKey:   "!",
+}
+
+var DefaultContentMarkers = Markers{
+	TemplateStart:      "▶",
+	TemplateEnd:        "◀",
+	TemplateChildStart: "⏩",
+	TemplateChildEnd:   "⏪",
+	TemplateBlockStart: "⏬",
+	TemplateBlockEnd:   "⏫",
+	TemplateCondition:  "↔",
+	TemplateSeparator:  "➡",
+	TemplateKey:        "⬇",
+	Escape:             "\\",
+	Comment:            "#",
+	Include:            "⤵",
+}
+
 func NewPathLexer(buf string) *Lexer {
-	return &Lexer{
-		buf: []rune(buf),
-		pos: 0,
-		words: map[rune]Token{
-			'{': TemplateStart("{"),
-			'}': TemplateEnd("}"),
-			'!': TemplateKey("!"),
-		},
-	}
+	return NewLexer(buf, DefaultPathMarkers)
 }
 
 func NewContentLexer(buf string) *Lexer {
-	return &Lexer{
-		buf: []rune(buf),
-		pos: 0,
-		words: map[rune]Token{
-			'▶': TemplateStart("▶"),
-			'◀': TemplateEnd("◀"),
-			'⏩': TemplateChildStart("⏩"),
-			'⏪': TemplateChildEnd("⏪"),
-			'↔': TemplateCondition("↔"),
-			'➡': TemplateSeparator("➡"),
-			'⬇': TemplateKey("⬇"),
-		},
+	return NewLexer(buf, DefaultContentMarkers)
+}
+
+func NewLexer(buf string, m Markers) *Lexer {
+	l := &Lexer{
+		buf:     []rune(buf),
+		pos:     0,
+		escape:  []rune(m.Escape),
+		comment: []rune(m.Comment),
+	}
+
+	tokens := []Token{
+		TemplateStart(m.TemplateStart),
+		TemplateEnd(m.TemplateEnd),
+		TemplateChildStart(m.TemplateChildStart),
+		TemplateChildEnd(m.TemplateChildEnd),
+		TemplateBlockStart(m.TemplateBlockStart),
+		TemplateBlockEnd(m.TemplateBlockEnd),
+		TemplateCondition(m.TemplateCondition),
+		TemplateSeparator(m.TemplateSeparator),
+		TemplateKey(m.TemplateKey),
 	}
+	for _, tok := range tokens {
+		if tok.String() != "" {
+			l.words = append(l.words, tok)
+		}
+	}
+
+	// the longest marker wins, so "[[[" is not read as "[[" and "["
+	sort.SliceStable(l.words, func(i, j int) bool {
+		return len([]rune(l.words[i].String())) > len([]rune(l.words[j].String()))
+	})
+
+	return l
 }
 
 type Lexer struct {
-	buf   []rune
-	pos   int
-	words map[rune]Token
+	buf     []rune
+	pos     int
+	escape  []rune
+	comment []rune
+	words   []Token
+	err     error
+}
+
+// Err returns the error of the malformed template, e.g. the comment that is not closed.
+func (l *Lexer) Err() error {
+	return l.err
 }
 
 type (
@@ -48,9 +107,12 @@ type (
 	TemplateEnd        string
 	TemplateChildStart string
 	TemplateChildEnd   string
+	TemplateBlockStart string
+	TemplateBlockEnd   string
 	TemplateKey        string
 	TemplateSeparator  string
 	TemplateCondition  string
+	Comment            string
 )
 
 type Token interface {
@@ -64,9 +126,12 @@ func (t TemplateStart) String() string      { return string(t) }
 func (t TemplateEnd) String() string        { return string(t) }
 func (t TemplateChildStart) String() string { return string(t) }
 func (t TemplateChildEnd) String() string   { return string(t) }
+func (t TemplateBlockStart) String() string { return string(t) }
+func (t TemplateBlockEnd) String() string   { return string(t) }
 func (t TemplateKey) String() string        { return string(t) }
 func (t TemplateSeparator) String() string  { return string(t) }
 func (t TemplateCondition) String() string  { return string(t) }
+func (c Comment) String() string            { return string(c) }
 
 const (
 	stateStart = iota
@@ -75,6 +140,7 @@ const (
 	stateTemplateSymbol
 	stateLineFeed
 	stateSeparator
+	stateComment
 )
 
 func (l *Lexer) NextToken() Token {
@@ -85,6 +151,7 @@ func (l *Lexer) NextToken() Token {
 		current, ok := l.nextRune()
 
 		var newState int
+		var marker Token
 
 		switch {
 		case !ok:
@@ -95,8 +162,12 @@ func (l *Lexer) NextToken() Token {
 			newState = stateLineFeed
 		case unicode.IsLetter(current) || unicode.IsDigit(current):
 			newState = stateWord
+		case l.isEscaped():
+			newState = stateWord
+		case l.isCommentStart():
+			newState = stateComment
 		default:
-			if _, ok := l.words[current]; ok {
+			if marker = l.markerAt(l.pos - 1); marker != nil {
 				newState = stateTemplateSymbol
 			} else {
 				newState = stateWord
@@ -116,11 +187,23 @@ func (l *Lexer) NextToken() Token {
 			if state != newState {
 				return Word(buf)
 			}
+			if l.isEscaped() {
+				l.pos += len(l.escape) - 1
+				marker := l.markerAt(l.pos)
+				size := len([]rune(marker.String()))
+				buf = append(buf, l.buf[l.pos:l.pos+size]...)
+				l.pos += size
+				break
+			}
 			buf = append(buf, current)
 		case stateTemplateSymbol:
-			w, _ := l.words[current]
-			return w
+			l.pos += len([]rune(marker.String())) - 1
+			return marker
 		case stateSeparator:
+			if newState == stateComment && l.commentEndsLine() {
+				l.pos++
+				return l.readComment() // the separator before the comment at the end of line is a part of the comment
+			}
 			if state != newState {
 				return Separator(buf)
 			}
@@ -130,6 +213,8 @@ func (l *Lexer) NextToken() Token {
 				return LineFeed(len(buf))
 			}
 			buf = append(buf, current)
+		case stateComment:
+			return l.readComment()
 		case stateEnd:
 			return nil
 		case stateStart:
@@ -138,6 +223,94 @@ func (l *Lexer) NextToken() Token {
 	}
 }
 
+// markerAt returns template marker which starts at the position.
+func (l *Lexer) markerAt(pos int) Token {
+	for _, w := range l.words {
+		if l.hasPrefixAt(pos, []rune(w.String())) {
+			return w
+		}
+	}
+	return nil
+}
+
+func (l *Lexer) hasPrefixAt(pos int, prefix []rune) bool {
+	if len(prefix) == 0 || pos+len(prefix) > len(l.buf) {
+		return false
+	}
+	for i, r := range prefix {
+		if l.buf[pos+i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// isEscaped reports whether the last read rune starts escape of a marker.
+func (l *Lexer) isEscaped() bool {
+	start := l.pos - 1
+	if !l.hasPrefixAt(start, l.escape) {
+		return false
+	}
+	return l.markerAt(start+len(l.escape)) != nil
+}
+
+// isCommentStart reports whether the last read rune starts a comment.
+func (l *Lexer) isCommentStart() bool {
+	start := l.pos - 1
+	if len(l.comment) == 0 {
+		return false
+	}
+	if _, ok := l.markerAt(start).(TemplateStart); !ok {
+		return false
+	}
+	return l.hasPrefixAt(start+len([]rune(l.markerAt(start).String())), l.comment)
+}
+
+// commentEndsLine reports whether the comment starting at the current position is followed by the end of line.
+func (l *Lexer) commentEndsLine() bool {
+	for pos := l.pos + len([]rune(l.markerAt(l.pos).String())) + len(l.comment); pos < len(l.buf); pos++ {
+		if _, ok := l.markerAt(pos).(TemplateEnd); ok {
+			end := pos + len([]rune(l.markerAt(pos).String()))
+			return end == len(l.buf) || l.buf[end] == '\n'
+		}
+	}
+	return false
+}
+
+// readComment reads the comment up to the end of template.
+// Comment that takes a whole line, possibly indented, is read together with its line feed.
+func (l *Lexer) readComment() Token {
+	start := l.pos - 1
+	l.pos += len([]rune(l.markerAt(start).String())) - 1 + len(l.comment)
+
+	var buf []rune
+	for {
+		if _, ok := l.markerAt(l.pos).(TemplateEnd); ok {
+			l.pos += len([]rune(l.markerAt(l.pos).String()))
+			break
+		}
+
+		current, ok := l.nextRune()
+		if !ok {
+			line, _, _ := strings.Cut(string(l.buf[start:]), "\n")
+			l.err = fmt.Errorf("lexer: readComment: comment is not closed: '%s'", line)
+			return nil
+		}
+		buf = append(buf, current)
+	}
+
+	lineStart := start
+	for lineStart > 0 && (l.buf[lineStart-1] == ' ' || l.buf[lineStart-1] == '\t') {
+		lineStart--
+	}
+	isLineStart := lineStart == 0 || l.buf[lineStart-1] == '\n'
+	if isLineStart && l.pos < len(l.buf) && l.buf[l.pos] == '\n' {
+		l.pos++
+	}
+
+	return Comment(buf)
+}
+
 func (l *Lexer) GoBack(i int) {
 	l.pos -= i
 }
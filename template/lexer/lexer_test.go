@@ -218,3 +218,153 @@ func (m *Model▶EntityName◀) To▶EntityName◀() dto.▶EntityName◀ {
 		actual,
 	)
 }
+
+func TestLexerEscape(t *testing.T) {
+	lexer := NewContentLexer(`play \▶ \⏩▶⬇Attribute➡Name◀\⏪ c:\dir`)
+
+	var actual []Token
+	for {
+		token := lexer.NextToken()
+		if token == nil {
+			break
+		}
+		actual = append(actual, token)
+	}
+
+	assert.Equal(
+		t,
+		[]Token{
+			Word("play"),
+			Separator(" "),
+			Word("▶"),
+			Separator(" "),
+			Word("⏩"),
+			TemplateStart("▶"),
+			TemplateKey("⬇"),
+			Word("Attribute"),
+			TemplateSeparator("➡"),
+			Word("Name"),
+			TemplateEnd("◀"),
+			Word("⏪"),
+			Separator(" "),
+			Word(`c:\dir`),
+		},
+		actual,
+	)
+}
+
+func TestLexerComment(t *testing.T) {
+	lexer := NewContentLexer(`▶# generated by maker, do not edit◀
+type ▶EntityName◀ struct { ▶#fields are added by attribute◀
+}
+`)
+
+	var actual []Token
+	for {
+		token := lexer.NextToken()
+		if token == nil {
+			break
+		}
+		actual = append(actual, token)
+	}
+
+	assert.Equal(
+		t,
+		[]Token{
+			Comment(" generated by maker, do not edit"),
+			Word("type"),
+			Separator(" "),
+			TemplateStart("▶"),
+			Word("EntityName"),
+			TemplateEnd("◀"),
+			Separator(" "),
+			Word("struct"),
+			Separator(" "),
+			Word("{"),
+			Comment("fields are added by attribute"),
+			LineFeed(1),
+			Word("}"),
+			LineFeed(1),
+		},
+		actual,
+	)
+}
+
+func TestLexerCommentIndented(t *testing.T) {
+	lexer := NewContentLexer("{\n\t▶# indented◀\n\tkey ▶# inline◀ value\n}")
+
+	var actual []Token
+	for token := lexer.NextToken(); token != nil; token = lexer.NextToken() {
+		actual = append(actual, token)
+	}
+
+	assert.Equal(t, []Token{
+		Word("{"),
+		LineFeed(1),
+		Comment(" indented"),
+		Separator("\t"),
+		Word("key"),
+		Separator(" "),
+		Comment(" inline"),
+		Separator(" "),
+		Word("value"),
+		LineFeed(1),
+		Word("}"),
+	}, actual)
+	assert.NoError(t, lexer.Err())
+}
+
+func TestLexerCommentNotClosed(t *testing.T) {
+	lexer := NewContentLexer("key ▶# not closed\nvalue\n")
+	assert.Equal(t, Word("key"), lexer.NextToken())
+	assert.Equal(t, Separator(" "), lexer.NextToken())
+	assert.Nil(t, lexer.NextToken())
+	assert.EqualError(t, lexer.Err(), "lexer: readComment: comment is not closed: '▶# not closed'")
+}
+
+func TestLexerMarkers(t *testing.T) {
+	lexer := NewLexer(`[[	{{@Attribute->Name}} {{Attribute->Nullable??*}}\{{]]`, Markers{
+		TemplateStart:      "{{",
+		TemplateEnd:        "}}",
+		TemplateChildStart: "[[",
+		TemplateChildEnd:   "]]",
+		TemplateCondition:  "??",
+		TemplateSeparator:  "->",
+		TemplateKey:        "@",
+		Escape:             `\`,
+	})
+
+	var actual []Token
+	for {
+		token := lexer.NextToken()
+		if token == nil {
+			break
+		}
+		actual = append(actual, token)
+	}
+
+	assert.Equal(
+		t,
+		[]Token{
+			TemplateChildStart("[["),
+			Separator("\t"),
+			TemplateStart("{{"),
+			TemplateKey("@"),
+			Word("Attribute"),
+			TemplateSeparator("->"),
+			Word("Name"),
+			TemplateEnd("}}"),
+			Separator(" "),
+			TemplateStart("{{"),
+			Word("Attribute"),
+			TemplateSeparator("->"),
+			Word("Nullable"),
+			TemplateCondition("??"),
+			Word("*"),
+			TemplateEnd("}}"),
+			Word("{{"),
+			TemplateChildEnd("]]"),
+		},
+		actual,
+	)
+}
@@ -1,6 +1,9 @@
 package template
 
-import "io/fs"
+import (
+	"fmt"
+	"io/fs"
+)
 
 type Namespace struct {
 	Name        string
@@ -10,14 +13,30 @@ type Namespace struct {
 	Paths       []Fs
 	Keys        []Fs
 	Values      map[string]bool
+	Config      *Config // set for the root namespace only
 }
 
-func New(fsys fs.FS, pathPrefix string) (*Namespace, error) {
+// New parses the template tree. Overlays are stacked on top of fsys, each of them
+// can add, replace or delete files of the previous layers and override their marked regions.
+func New(fsys fs.FS, pathPrefix string, overlays ...fs.FS) (*Namespace, error) {
 	if pathPrefix == "" {
 		pathPrefix = "."
 	}
 
-	dir, err := parseDir(fsys, pathPrefix)
+	if len(overlays) > 0 {
+		fsys = newOverlay(append([]fs.FS{fsys}, overlays...))
+	}
+
+	cfg, err := readConfig(fsys, pathPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if o, ok := fsys.(*overlay); ok {
+		o.markers = cfg.Markers.Content
+	}
+
+	dir, err := parseDir(fsys, pathPrefix, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -35,8 +54,68 @@ func New(fsys fs.FS, pathPrefix string) (*Namespace, error) {
 		nil,
 		nil,
 		make(map[string]bool),
+		cfg,
 	}
 	analyze(dir, nspace)
 
+	if err := checkReferences(nspace); err != nil {
+		return nil, err
+	}
+
 	return nspace, nil
 }
+
+// Find returns the namespace or its descendant by the name, nil if it doesn't exist.
+func (n *Namespace) Find(name string) *Namespace {
+	if n.Name == name {
+		return n
+	}
+	for _, child := range n.Children {
+		if found := child.Find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func checkReferences(root *Namespace) error {
+	for _, ref := range root.Config.References {
+		fromNspace, fromValue := ref.FromNamespace()
+		toNspace, toValue := ref.ToNamespace()
+
+		for _, nv := range [][2]string{{fromNspace, fromValue}, {toNspace, toValue}} {
+			nspace := root.Find(nv[0])
+			if nspace == nil {
+				return fmt.Errorf("template: New: reference '%s' -> '%s': namespace '%s' does not exist", ref.From, ref.To, nv[0])
+			}
+			if !nspace.Values[nv[1]] {
+				return fmt.Errorf("template: New: reference '%s' -> '%s': namespace '%s' has no value '%s'", ref.From, ref.To, nv[0], nv[1])
+			}
+		}
+
+		if ref.Scope == "" {
+			continue
+		}
+		scope := root.Find(ref.Scope)
+		if scope == nil {
+			return fmt.Errorf("template: New: reference '%s' -> '%s': scope namespace '%s' does not exist", ref.From, ref.To, ref.Scope)
+		}
+		for _, name := range []string{fromNspace, toNspace} {
+			if !scope.contains(root.Find(name)) {
+				return fmt.Errorf("template: New: reference '%s' -> '%s': namespace '%s' is out of scope '%s'", ref.From, ref.To, name, ref.Scope)
+			}
+		}
+	}
+
+	return nil
+}
+
+// contains reports whether the namespace is a descendant of n.
+func (n *Namespace) contains(nspace *Namespace) bool {
+	for nspace = nspace.Parent; nspace != nil; nspace = nspace.Parent {
+		if nspace == n {
+			return true
+		}
+	}
+	return false
+}
@@ -3,8 +3,13 @@ package template
 import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vologzhan/maker/template/lexer"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestNew(t *testing.T) {
@@ -29,7 +34,7 @@ func TestNew(t *testing.T) {
 	sql := service.Children["sql"]
 	assert.Equal(t, "sql", sql.Name)
 	assert.Equal(t, 2, len(sql.Entrypoints))
-	assert.Equal(t, 0, len(sql.Keys)) // unreadable
+	assert.Equal(t, 2, len(sql.Keys))
 	assert.Equal(t, 2, len(sql.Paths))
 	assert.Equal(t, 0, len(sql.Children))
 
@@ -55,3 +60,211 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, 0, len(relation.Children))
 
 }
+
+func TestNewWithMarkersConfig(t *testing.T) {
+	pathReplacer := strings.NewReplacer("{", "[", "}", "]", "!", "@")
+	contentReplacer := strings.NewReplacer("▶", "{{", "◀", "}}", "⏩", "[[", "⏪", "]]", "⬇", "@", "➡", "->", "↔", "??")
+
+	fsys := fstest.MapFS{
+		ConfigFile: {Data: []byte(`markers:
+  path:
+    template_start: "["
+    template_end: "]"
+    key: "@"
+  content:
+    template_start: "{{"
+    template_end: "}}"
+    child_start: "[["
+    child_end: "]]"
+    key: "@"
+    separator: "->"
+    condition: "??"
+`)},
+	}
+	err := fs.WalkDir(os.DirFS("../_test/_template-go"), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(filepath.Join("../_test/_template-go", path))
+		if err != nil {
+			return err
+		}
+		fsys[pathReplacer.Replace(path)] = &fstest.MapFile{Data: []byte(contentReplacer.Replace(string(content)))}
+		return nil
+	})
+	require.NoError(t, err)
+
+	expected, err := New(os.DirFS("../_test/_template-go"), "")
+	require.NoError(t, err)
+
+	actual, err := New(fsys, "")
+	require.NoError(t, err)
+	assert.Equal(t, "[[", actual.Config.Markers.Content.TemplateChildStart)
+	assert.Equal(t, "⏬", actual.Config.Markers.Content.TemplateBlockStart)
+
+	assertNamespaceEqual(t, expected, actual)
+}
+
+func TestNewWithCommentNotClosed(t *testing.T) {
+	_, err := New(fstest.MapFS{
+		"{!service-name}/go.mod.t": {Data: []byte("module ▶service-name◀ ▶# the name of the service\n")},
+	}, "")
+	assert.EqualError(t, err, "template: parseFile: '{!service-name}/go.mod.t': lexer: readComment: comment is not closed: '▶# the name of the service'")
+}
+
+func TestReadConfigMarkers(t *testing.T) {
+	cfg, err := readConfig(fstest.MapFS{
+		ConfigFile: {Data: []byte(`markers:
+  path:
+  content:
+    template_start: "{{"
+    template_end: "}}"
+    comment: ""
+`)},
+	}, ".")
+	require.NoError(t, err)
+
+	assert.Equal(t, lexer.DefaultPathMarkers, cfg.Markers.Path, "missing markers stay default")
+
+	expected := lexer.DefaultContentMarkers
+	expected.TemplateStart, expected.TemplateEnd, expected.Comment = "{{", "}}", ""
+	assert.Equal(t, expected, cfg.Markers.Content, "set markers are merged into the default ones, empty disables the marker")
+
+	cfg, err = readConfig(fstest.MapFS{}, ".")
+	require.NoError(t, err)
+	assert.Equal(t, ConfigMarkers{lexer.DefaultPathMarkers, lexer.DefaultContentMarkers}, cfg.Markers)
+}
+
+func TestReadConfigInvalidMarkers(t *testing.T) {
+	tests := []struct {
+		name, config, expected string
+	}{
+		{"letter", "markers:\n  content:\n    template_start: \"a\"\n", "template: readConfig: content marker 'template_start' contains a letter or digit: 'a'"},
+		{"digit", "markers:\n  path:\n    key: \"!1\"\n", "template: readConfig: path marker 'key' contains a letter or digit: '!1'"},
+		{"empty start", "markers:\n  content:\n    template_start: \"\"\n", "template: readConfig: content markers 'template_start' and 'template_end' are required"},
+		{"empty end", "markers:\n  path:\n    template_end: \"\"\n", "template: readConfig: path markers 'template_start' and 'template_end' are required"},
+		{"child end only", "markers:\n  content:\n    child_start: \"\"\n", "template: readConfig: content markers 'child_start' and 'child_end' are set only together"},
+		{"duplicate", "markers:\n  content:\n    template_start: \"⏩\"\n", "template: readConfig: content markers 'template_start' and 'child_start' are the same: '⏩'"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New(fstest.MapFS{
+				ConfigFile:                 {Data: []byte(test.config)},
+				"{!service-name}/go.mod.t": {Data: []byte("module ▶service-name◀\n")},
+			}, "")
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func TestNewWithReferenceOutOfScope(t *testing.T) {
+	_, err := New(os.DirFS("../_test/_template-go"), "", fstest.MapFS{
+		ConfigFile: {Data: []byte("references:\n  - from: attribute.fk_table\n    to: entity.name_db\n    scope: entity\n")},
+	})
+	assert.EqualError(t, err, "template: New: reference 'attribute.fk_table' -> 'entity.name_db': namespace 'entity' is out of scope 'entity'")
+}
+
+func assertNamespaceEqual(t *testing.T, expected, actual *Namespace) {
+	assert.Equal(t, expected.Name, actual.Name)
+	assert.Equal(t, len(expected.Entrypoints), len(actual.Entrypoints), expected.Name)
+	assert.Equal(t, len(expected.Keys), len(actual.Keys), expected.Name)
+	assert.Equal(t, len(expected.Paths), len(actual.Paths), expected.Name)
+	assert.Equal(t, expected.Values, actual.Values, expected.Name)
+	require.Equal(t, len(expected.Children), len(actual.Children), expected.Name)
+
+	for name, child := range expected.Children {
+		require.Contains(t, actual.Children, name)
+		assertNamespaceEqual(t, child, actual.Children[name])
+	}
+}
+
+func TestNewWithPartials(t *testing.T) {
+	viper := `package env
+
+import "github.com/spf13/viper"
+
+func Postgres() string {
+	return viper.GetString("▶SERVICE_NAME◀_POSTGRES")
+}
+`
+	goMod := `go 1.25.4
+
+require github.com/spf13/viper v1.21.0
+`
+
+	expected, err := New(fstest.MapFS{
+		"{!service-name}/http/go.mod.t":   {Data: []byte("module ▶service-name◀/http\n\n" + goMod)},
+		"{!service-name}/http/viper.go.t": {Data: []byte(viper)},
+		"{!service-name}/go.mod.t":        {Data: []byte("module ▶service-name◀\n\n" + goMod)},
+		"{!service-name}/viper.go.t":      {Data: []byte(viper)},
+	}, "")
+	require.NoError(t, err)
+
+	actual, err := New(fstest.MapFS{
+		"_partials/viper.go.t":            {Data: []byte(viper)},
+		"_partials/go.mod.t":              {Data: []byte(goMod)},
+		"{!service-name}/http/go.mod.t":   {Data: []byte("module ▶service-name◀/http\n\n▶⤵go.mod.t◀\n")},
+		"{!service-name}/http/viper.go.t": {Data: []byte("▶⤵viper.go.t◀\n")},
+		"{!service-name}/go.mod.t":        {Data: []byte("module ▶service-name◀\n\n▶⤵go.mod.t◀\n")},
+		"{!service-name}/viper.go.t":      {Data: []byte("▶⤵ viper.go.t ◀\n")},
+	}, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, len(actual.Entrypoints[0].(*Dir).Items)) // partials are not generated
+	assertNamespaceEqual(t, expected, actual)
+
+	_, err = New(fstest.MapFS{
+		"_partials/a.t": {Data: []byte("▶⤵b.t◀\n")},
+		"_partials/b.t": {Data: []byte("▶⤵a.t◀\n")},
+		"file.t":        {Data: []byte("▶⤵a.t◀\n")},
+	}, "")
+	assert.EqualError(t, err, "template: readPartial: cyclic include of '_partials/a.t'")
+
+	actual, err = New(fstest.MapFS{
+		"templates/_partials/viper.go.t":            {Data: []byte(viper)},
+		"templates/_partials/go.mod.t":              {Data: []byte(goMod)},
+		"templates/{!service-name}/http/go.mod.t":   {Data: []byte("module ▶service-name◀/http\n\n▶⤵go.mod.t◀\n")},
+		"templates/{!service-name}/http/viper.go.t": {Data: []byte("▶⤵viper.go.t◀\n")},
+		"templates/{!service-name}/go.mod.t":        {Data: []byte("module ▶service-name◀\n\n▶⤵go.mod.t◀\n")},
+		"templates/{!service-name}/viper.go.t":      {Data: []byte("▶⤵ viper.go.t ◀\n")},
+	}, "templates")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, len(actual.Entrypoints[0].(*Dir).Items), "partials are resolved from the path prefix")
+	assertNamespaceEqual(t, expected, actual)
+}
+
+func TestNewWithOverlays(t *testing.T) {
+	base := fstest.MapFS{
+		"{!service-name}/go.mod.t":    {Data: []byte("module ▶service-name◀\n")},
+		"{!service-name}/readme.md.t": {Data: []byte("# ▶service-name◀\n")},
+		"{!service-name}/Makefile.t":  {Data: []byte("build:\n\tgo build ./...\n")},
+		"{!service-name}/config.yaml.t": {Data: []byte("app: ▶service-name◀\n" +
+			"▶#region deps◀\n" +
+			"postgres: true\n" +
+			"▶#endregion◀\n")},
+	}
+	kafka := fstest.MapFS{
+		"{!service-name}/readme.md.t":            {Data: []byte("# ▶service-name◀ with kafka\n")},
+		"{!service-name}/Makefile.t.delete":      {Data: nil},
+		"{!service-name}/kafka.yaml.t":           {Data: []byte("topic: ▶service-name◀\n")},
+		"{!service-name}/config.yaml.t.override": {Data: []byte("▶#region deps◀\npostgres: true\nkafka: true\n▶#endregion◀\n")},
+	}
+
+	expected, err := New(fstest.MapFS{
+		"{!service-name}/go.mod.t":      {Data: []byte("module ▶service-name◀\n")},
+		"{!service-name}/readme.md.t":   {Data: []byte("# ▶service-name◀ with kafka\n")},
+		"{!service-name}/kafka.yaml.t":  {Data: []byte("topic: ▶service-name◀\n")},
+		"{!service-name}/config.yaml.t": {Data: []byte("app: ▶service-name◀\npostgres: true\nkafka: true\n")},
+	}, "")
+	require.NoError(t, err)
+
+	actual, err := New(base, "", kafka)
+	require.NoError(t, err)
+	assertNamespaceEqual(t, expected, actual)
+
+	_, err = New(base, "", fstest.MapFS{
+		"{!service-name}/config.yaml.t.override": {Data: []byte("▶#region unknown◀\n▶#endregion◀\n")},
+	})
+	assert.EqualError(t, err, "template: overlay: file '{!service-name}/config.yaml.t': region 'unknown' not found")
+}
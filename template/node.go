@@ -1,12 +1,23 @@
 package template
 
-import "errors"
+import (
+	"errors"
+	"github.com/vologzhan/maker/template/lexer"
+)
 
 type FileType int
 
 const (
 	FileUnknown FileType = iota
 	FileGo
+	FileYaml
+	FileEnv
+	FileSql
+	FileGoMod
+	FileJson
+	FileMarkdown
+	FileProto
+	FileTs
 )
 
 type (
@@ -23,10 +34,13 @@ type (
 		Content []Node
 		Entry   bool
 		parent  Node
+		Format  *ConfigFormat
+		Markers *lexer.Markers // markers of the content
 	}
 	Template struct {
 		Items  []Node
 		Entry  bool
+		Block  bool
 		parent Node
 	}
 	Insert struct {
@@ -41,6 +55,7 @@ type (
 	Imports struct {
 		Items  []*Import
 		parent Node
+		ByType map[string]string // imports of qualified types, see Config.Imports
 	}
 	Import struct {
 		Name   []Node
@@ -122,6 +137,15 @@ func IsEntry(tpl Node) bool {
 	}
 }
 
+// EndsWithEntry reports whether the child template is closed together with
+// its last nested child template, so it has no line feed of its own.
+func EndsWithEntry(tpl *Template) bool {
+	if len(tpl.Items) == 0 {
+		return false
+	}
+	return IsEntry(tpl.Items[len(tpl.Items)-1])
+}
+
 func IsChildOrCurrent(parent Node, child Node) bool {
 	if parent == child {
 		return true
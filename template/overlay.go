@@ -0,0 +1,301 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/vologzhan/maker/template/lexer"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// overlayDelete marks file or directory of the previous layers as deleted, e.g. "main.go.t.delete".
+	overlayDelete = ".delete"
+	// overlayOverride overrides marked regions of the file of the previous layers, e.g. "main.go.t.override".
+	overlayOverride = ".override"
+)
+
+// overlay merges layers of template trees, later layers add, replace or delete files of earlier ones
+// and override regions marked with comments "▶#region name◀" ... "▶#endregion◀".
+type overlay struct {
+	layers  []fs.FS
+	markers lexer.Markers
+}
+
+func newOverlay(layers []fs.FS) *overlay {
+	return &overlay{layers, lexer.DefaultContentMarkers}
+}
+
+func (o *overlay) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	entries, err := o.ReadDir(name)
+	if err == nil {
+		return &overlayDir{overlayInfo{path.Base(name), 0, true}, entries}, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	content, err := o.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &overlayFile{overlayInfo{path.Base(name), int64(len(content)), false}, bytes.NewReader(content)}, nil
+}
+
+func (o *overlay) ReadFile(name string) ([]byte, error) {
+	var content []byte
+	found := false
+
+	for _, layer := range o.layers {
+		if isDeletedInLayer(layer, name) {
+			content = nil
+			found = false
+		}
+
+		layerContent, ok, err := readLayerFile(layer, name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			content = layerContent
+			found = true
+		}
+
+		override, ok, err := readLayerFile(layer, name+overlayOverride)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if !found {
+			return nil, fmt.Errorf("template: overlay: override of not existing file '%s'", name)
+		}
+
+		content, err = overrideRegions(content, override, o.markers)
+		if err != nil {
+			return nil, fmt.Errorf("template: overlay: file '%s': %w", name, err)
+		}
+	}
+
+	if !found {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return content, nil
+}
+
+func (o *overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries := make(map[string]fs.DirEntry)
+	found := false
+
+	for _, layer := range o.layers {
+		if isDeletedInLayer(layer, name) {
+			entries = make(map[string]fs.DirEntry)
+			found = false
+		}
+
+		info, err := fs.Stat(layer, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			continue
+		}
+
+		layerEntries, err := fs.ReadDir(layer, name)
+		if err != nil {
+			return nil, err
+		}
+		found = true
+
+		for _, entry := range layerEntries {
+			if deleted, ok := strings.CutSuffix(entry.Name(), overlayDelete); ok {
+				delete(entries, deleted)
+			}
+		}
+		for _, entry := range layerEntries {
+			if strings.HasSuffix(entry.Name(), overlayDelete) || strings.HasSuffix(entry.Name(), overlayOverride) {
+				continue
+			}
+			entries[entry.Name()] = entry
+		}
+	}
+
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	out := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+
+	return out, nil
+}
+
+func isDeletedInLayer(layer fs.FS, name string) bool {
+	for current := name; current != "." && current != "/"; current = path.Dir(current) {
+		if _, err := fs.Stat(layer, current+overlayDelete); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func readLayerFile(layer fs.FS, name string) ([]byte, bool, error) {
+	info, err := fs.Stat(layer, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if info.IsDir() {
+		return nil, false, nil
+	}
+
+	content, err := fs.ReadFile(layer, name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return content, true, nil
+}
+
+type region struct {
+	name  string
+	start int // start of the region body
+	end   int // end of the region body
+}
+
+// overrideRegions replaces bodies of the regions in content by bodies of the same regions in override.
+func overrideRegions(content, override []byte, m lexer.Markers) ([]byte, error) {
+	overrideRegions, err := findRegions(string(override), m)
+	if err != nil {
+		return nil, err
+	}
+
+	out := string(content)
+	for _, r := range overrideRegions {
+		regions, err := findRegions(out, m)
+		if err != nil {
+			return nil, err
+		}
+
+		replaced := false
+		for _, current := range regions {
+			if current.name != r.name {
+				continue
+			}
+			out = out[:current.start] + string(override[r.start:r.end]) + out[current.end:]
+			replaced = true
+			break
+		}
+
+		if !replaced {
+			return nil, fmt.Errorf("region '%s' not found", r.name)
+		}
+	}
+
+	return []byte(out), nil
+}
+
+func findRegions(s string, m lexer.Markers) ([]region, error) {
+	start := m.TemplateStart + m.Comment + "region "
+	end := m.TemplateStart + m.Comment + "endregion" + m.TemplateEnd
+
+	var out []region
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], start)
+		if i == -1 {
+			break
+		}
+		i += offset
+
+		j := strings.Index(s[i:], m.TemplateEnd)
+		if j == -1 {
+			return nil, fmt.Errorf("region is not closed: '%s'", s[i:])
+		}
+		name := strings.TrimSpace(s[i+len(start) : i+j])
+		bodyStart := i + j + len(m.TemplateEnd)
+
+		k := strings.Index(s[bodyStart:], end)
+		if k == -1 {
+			return nil, fmt.Errorf("region '%s' has no end", name)
+		}
+
+		out = append(out, region{name, bodyStart, bodyStart + k})
+		offset = bodyStart + k + len(end)
+	}
+
+	return out, nil
+}
+
+type overlayInfo struct {
+	name  string
+	size  int64
+	isDir bool
+}
+
+func (i overlayInfo) Name() string       { return i.name }
+func (i overlayInfo) Size() int64        { return i.size }
+func (i overlayInfo) ModTime() time.Time { return time.Time{} }
+func (i overlayInfo) IsDir() bool        { return i.isDir }
+func (i overlayInfo) Sys() any           { return nil }
+func (i overlayInfo) Mode() fs.FileMode {
+	if i.isDir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type overlayFile struct {
+	info   overlayInfo
+	reader *bytes.Reader
+}
+
+func (f *overlayFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *overlayFile) Read(b []byte) (int, error) { return f.reader.Read(b) }
+func (f *overlayFile) Close() error               { return nil }
+
+type overlayDir struct {
+	info    overlayInfo
+	entries []fs.DirEntry
+}
+
+func (d *overlayDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *overlayDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+func (d *overlayDir) Close() error { return nil }
+func (d *overlayDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		out := d.entries
+		d.entries = nil
+		return out, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	out := d.entries[:n]
+	d.entries = d.entries[n:]
+	return out, nil
+}
@@ -9,9 +9,9 @@ import (
 	"strings"
 )
 
-func parseDir(fsys fs.FS, path string) (*Dir, error) {
+func parseDir(fsys fs.FS, path string, cfg *Config) (*Dir, error) {
 	dir := &Dir{
-		Name: parseName(filepath.Base(path)),
+		Name: parseName(filepath.Base(path), cfg),
 	}
 
 	entries, err := fs.ReadDir(fsys, path)
@@ -20,13 +20,23 @@ func parseDir(fsys fs.FS, path string) (*Dir, error) {
 	}
 
 	for _, entry := range entries {
+		if entry.Name() == ConfigFile {
+			continue
+		}
+
 		entryPath := filepath.Join(path, entry.Name())
+		if entryPath == cfg.Partials {
+			continue
+		}
 
 		var item Node
 		if entry.IsDir() {
-			item, err = parseDir(fsys, entryPath)
+			item, err = parseDir(fsys, entryPath, cfg)
 		} else {
-			item, err = parseFile(fsys, entryPath)
+			item, err = parseFile(fsys, entryPath, cfg)
+		}
+		if err != nil {
+			return nil, err
 		}
 
 		dir.Items = append(dir.Items, item)
@@ -35,12 +45,14 @@ func parseDir(fsys fs.FS, path string) (*Dir, error) {
 	return dir, nil
 }
 
-func parseFile(fsys fs.FS, path string) (*File, error) {
+func parseFile(fsys fs.FS, path string, cfg *Config) (*File, error) {
 	pathPrepared := strings.TrimSuffix(path, ".t")
 	name := filepath.Base(pathPrepared)
 
 	file := &File{
-		Name: parseName(name),
+		Name:    parseName(name, cfg),
+		Format:  &cfg.Format,
+		Markers: &cfg.Markers.Content,
 	}
 
 	content, err := fs.ReadFile(fsys, path)
@@ -48,36 +60,84 @@ func parseFile(fsys fs.FS, path string) (*File, error) {
 		return nil, err
 	}
 
-	switch filepath.Ext(name) {
-	case ".go":
-		file.Type = FileGo
-		file.Content = parseContentGo(content)
+	content, err = expandIncludes(fsys, content, cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	file.Type = fileType(name)
+	switch file.Type {
+	case FileGo:
+		file.Content, err = parseContentGo(content, cfg)
+		for _, node := range file.Content {
+			if imps, ok := node.(*Imports); ok {
+				imps.ByType = cfg.Imports
+			}
+		}
+	case FileTs:
+		file.Content, err = parseContentTs(content, cfg)
 	default:
-		file.Type = FileUnknown
-		file.Content = parseContent(content)
+		file.Content, err = parseContent(content, cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("template: parseFile: '%s': %w", path, err)
 	}
 
 	return file, nil
 }
 
-func parseName(name string) []Node {
+func fileType(name string) FileType {
+	if name == ".env" || strings.HasPrefix(name, ".env.") {
+		return FileEnv
+	}
+	if name == "go.mod" {
+		return FileGoMod
+	}
+
+	switch filepath.Ext(name) {
+	case ".go":
+		return FileGo
+	case ".yaml", ".yml":
+		return FileYaml
+	case ".env":
+		return FileEnv
+	case ".sql":
+		return FileSql
+	case ".json":
+		return FileJson
+	case ".md":
+		return FileMarkdown
+	case ".proto":
+		return FileProto
+	case ".ts", ".tsx", ".js", ".jsx", ".mjs":
+		return FileTs
+	default:
+		return FileUnknown
+	}
+}
+
+func parseName(name string, cfg *Config) []Node {
 	return parse(&strategyBase{
-		lexer: lexer.NewPathLexer(name),
+		lexer: lexer.NewLexer(name, cfg.Markers.Path),
 	})
 }
 
-func parseContent(content []byte) []Node {
-	return parse(&strategyBase{
-		lexer: lexer.NewContentLexer(string(content)),
+func parseContent(content []byte, cfg *Config) ([]Node, error) {
+	l := lexer.NewLexer(string(content), cfg.Markers.Content)
+	nodes := parse(&strategyBase{
+		lexer: l,
 	})
+	return nodes, l.Err()
 }
 
-func parseContentGo(content []byte) []Node {
-	return parse(&strategyFileGo{
+func parseContentGo(content []byte, cfg *Config) ([]Node, error) {
+	l := lexer.NewLexer(string(content), cfg.Markers.Content)
+	nodes := parse(&strategyFileGo{
 		strategyBase: strategyBase{
-			lexer: lexer.NewContentLexer(string(content)),
+			lexer: l,
 		},
 	})
+	return nodes, l.Err()
 }
 
 func parse(s strategy) []Node {
@@ -108,7 +168,14 @@ type strategyBase struct {
 	lexer *lexer.Lexer
 }
 
-func (s *strategyBase) nextToken() lexer.Token { return s.lexer.NextToken() }
+func (s *strategyBase) nextToken() lexer.Token {
+	for {
+		tok := s.lexer.NextToken()
+		if _, ok := tok.(lexer.Comment); !ok {
+			return tok
+		}
+	}
+}
 
 func (s *strategyBase) handle(tok lexer.Token) interface{} {
 	if s.child != nil {
@@ -142,6 +209,13 @@ func (s *strategyBase) handle(tok lexer.Token) interface{} {
 				lexer: s.lexer,
 			},
 		}
+	case lexer.TemplateBlockStart:
+		s.child = &strategyTemplateEntry{
+			strategyBase: strategyBase{
+				lexer: s.lexer,
+			},
+			block: true,
+		}
 	default:
 		panic(fmt.Sprintf("strategy base, unexpected token type: '%T'", token))
 	}
@@ -213,7 +287,7 @@ func (s *strategyTemplate) handle(tok lexer.Token) interface{} {
 				nil,
 			}
 		case stateSimpleTemplate:
-			return &Template{s.buf, false, nil}
+			return &Template{s.buf, false, false, nil}
 		case stateInsertWithFunc:
 			if s.hasKey && len(s.buf) == 0 {
 				return &key{}
@@ -249,6 +323,7 @@ func (s *strategyTemplate) handle(tok lexer.Token) interface{} {
 
 type strategyTemplateEntry struct {
 	strategyBase
+	block bool
 }
 
 func (s *strategyTemplateEntry) handle(tok lexer.Token) interface{} {
@@ -263,16 +338,28 @@ func (s *strategyTemplateEntry) handle(tok lexer.Token) interface{} {
 	}
 
 	switch token := tok.(type) {
-	case lexer.Word, lexer.Separator, lexer.LineFeed, lexer.TemplateStart:
+	case lexer.Word, lexer.Separator, lexer.LineFeed, lexer.TemplateStart, lexer.TemplateChildStart, lexer.TemplateBlockStart:
 		s.strategyBase.handle(token)
-	case lexer.TemplateChildEnd:
-		lf, ok := s.lexer.NextToken().(lexer.LineFeed)
-		if !ok {
+	case lexer.TemplateChildEnd, lexer.TemplateBlockEnd:
+		if _, isBlockEnd := token.(lexer.TemplateBlockEnd); isBlockEnd != s.block {
+			panic(fmt.Sprintf("strategy template entry, unexpected end of template: '%s'", token))
+		}
+
+		switch next := s.lexer.NextToken().(type) {
+		case lexer.LineFeed:
+			s.lexer.GoBack(int(next) - 1)
+		case lexer.TemplateChildEnd, lexer.TemplateBlockEnd:
+			s.lexer.GoBack(len([]rune(next.String()))) // nested template ends together with parent
+		default:
 			panic("entry template must end with line feed")
 		}
-		s.lexer.GoBack(int(lf) - 1)
 
-		return &Template{s.buf, true, nil}
+		// block contains whole lines, so it always ends with its own line feed
+		if s.block && !endsWithLine(s.buf) {
+			s.buf = append(s.buf, &LineFeed{1, nil})
+		}
+
+		return &Template{s.buf, true, s.block, nil}
 	default:
 		panic(fmt.Sprintf("strategy template entry, unexpected token type: '%T'", token))
 	}
@@ -280,6 +367,16 @@ func (s *strategyTemplateEntry) handle(tok lexer.Token) interface{} {
 	return nil
 }
 
+func endsWithLine(items []Node) bool {
+	if len(items) == 0 {
+		return false
+	}
+	if _, ok := items[len(items)-1].(*LineFeed); ok {
+		return true
+	}
+	return IsEntry(items[len(items)-1])
+}
+
 type strategyFileGo struct {
 	strategyBase
 	state stateFileGo
@@ -310,7 +407,14 @@ func (s *strategyFileGo) handle(tok lexer.Token) interface{} {
 	}
 
 	switch token := tok.(type) {
-	case lexer.Separator, lexer.TemplateStart, lexer.TemplateChildStart:
+	case lexer.Separator:
+		return s.strategyBase.handle(tok)
+	case lexer.TemplateStart, lexer.TemplateChildStart, lexer.TemplateBlockStart:
+		if s.state == stateExpectedWordImport {
+			s.buf = append(s.buf, &Imports{})
+			s.buf = append(s.buf, &LineFeed{1, nil})
+			s.state = stateNormal
+		}
 		return s.strategyBase.handle(tok)
 	case lexer.Word:
 		if s.state == stateExpectedWordImport {
@@ -416,6 +520,7 @@ func (s *strategyImport) handle(tok lexer.Token) interface{} {
 					},
 				},
 				nil,
+				nil,
 			}
 		}
 		if len(s.buf) > 0 {
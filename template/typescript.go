@@ -0,0 +1,143 @@
+package template
+
+import (
+	"slices"
+	"strings"
+)
+
+// parseContentTs parses the content and collects the import declarations at the top of the file,
+// after leading comment lines, to Imports: the module is the name of the import and the import clause is its alias.
+// An import declaration may be a child template, e.g. ⏩import { ▶⬇EntityName◀ } from './▶⬇entity-name◀';⏪.
+func parseContentTs(content []byte, cfg *Config) ([]Node, error) {
+	nodes, err := parseContent(content, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	i := 0
+	for i < len(nodes) && isTsComment(nodes[i]) {
+		for i < len(nodes) {
+			_, isLineFeed := nodes[i].(*LineFeed)
+			i++
+			if isLineFeed {
+				break
+			}
+		}
+	}
+
+	imps := &Imports{}
+	end := i      // the index after the last import
+	var rest Node // blank lines after the last import, if they share the line feed of the import
+	for j := i; j < len(nodes); {
+		if _, ok := nodes[j].(*LineFeed); ok {
+			j++ // blank lines between imports
+			continue
+		}
+
+		if tpl, ok := nodes[j].(*Template); ok {
+			if !tpl.Entry || !isTsImport(tpl.Items) {
+				break
+			}
+			imps.Items = append(imps.Items, newTsImport(tpl.Items, true))
+			j++
+			end, rest = j, nil
+			continue
+		}
+
+		if !isTsImport(nodes[j:]) {
+			break
+		}
+
+		k := j
+		for k < len(nodes) {
+			if _, ok := nodes[k].(*LineFeed); ok {
+				break
+			}
+			k++
+		}
+		imps.Items = append(imps.Items, newTsImport(nodes[j:k], false))
+		end, rest = k, nil
+		if k < len(nodes) {
+			end = k + 1
+			if lf := nodes[k].(*LineFeed); lf.Value > 1 {
+				rest = &LineFeed{lf.Value - 1, nil}
+			}
+		}
+		j = end
+	}
+
+	if len(imps.Items) == 0 {
+		return nodes, nil
+	}
+
+	out := append(slices.Clone(nodes[:i]), imps)
+	if rest != nil {
+		out = append(out, rest)
+	}
+	return append(out, nodes[end:]...), nil
+}
+
+func isTsComment(node Node) bool {
+	w, ok := node.(*Word)
+	return ok && strings.HasPrefix(w.Value, "//")
+}
+
+func isTsImport(nodes []Node) bool {
+	if len(nodes) == 0 {
+		return false
+	}
+	w, ok := nodes[0].(*Word)
+	return ok && w.Value == "import"
+}
+
+// newTsImport returns the import of the declaration "import clause from 'module';" or "import 'module';",
+// the quotes and the semicolon are dropped.
+func newTsImport(nodes []Node, entry bool) *Import {
+	from := -1
+	for i, node := range nodes {
+		if w, ok := node.(*Word); ok && w.Value == "from" {
+			from = i
+			break
+		}
+	}
+
+	imp := &Import{Entry: entry}
+	module := nodes[1:]
+	if from != -1 {
+		imp.Alias = trimSeparators(nodes[1:from])
+		module = nodes[from+1:]
+	}
+	module = trimSeparators(module)
+
+	if len(module) > 0 {
+		if w, ok := module[0].(*Word); ok {
+			module[0] = &Word{strings.TrimLeft(w.Value, `'"`), nil}
+		}
+		if w, ok := module[len(module)-1].(*Word); ok {
+			module[len(module)-1] = &Word{strings.TrimRight(w.Value, `'";`), nil}
+		}
+	}
+	for _, node := range module {
+		if w, ok := node.(*Word); !ok || w.Value != "" {
+			imp.Name = append(imp.Name, node)
+		}
+	}
+
+	return imp
+}
+
+func trimSeparators(nodes []Node) []Node {
+	for len(nodes) > 0 {
+		if _, ok := nodes[0].(*Separator); !ok {
+			break
+		}
+		nodes = nodes[1:]
+	}
+	for len(nodes) > 0 {
+		if _, ok := nodes[len(nodes)-1].(*Separator); !ok {
+			break
+		}
+		nodes = nodes[:len(nodes)-1]
+	}
+	return slices.Clone(nodes)
+}
@@ -0,0 +1,408 @@
+package maker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/vologzhan/maker/source"
+	"github.com/vologzhan/maker/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type upgradeNode struct {
+	namespace string
+	id        uuid.UUID
+	values    map[string]string
+	children  []*upgradeNode
+}
+
+// Upgrade migrates the project generated with oldTpl to newTpl.
+// The project is read with the old template, rendered with both templates to temporary directories
+// and the difference between the renders is applied to the project, so hand-written lines are preserved.
+func Upgrade(oldTpl, newTpl *template.Namespace, srcPath string) error {
+	project, err := New(oldTpl, srcPath)
+	if err != nil {
+		return err
+	}
+
+	tree, err := readUpgradeTree(project)
+	if err != nil {
+		return err
+	}
+
+	baseDir, err := os.MkdirTemp("", "maker-upgrade-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(baseDir)
+
+	nextDir, err := os.MkdirTemp("", "maker-upgrade-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(nextDir)
+
+	if err := renderUpgradeTree(oldTpl, baseDir, tree); err != nil {
+		return err
+	}
+	if err := renderUpgradeTree(newTpl, nextDir, tree); err != nil {
+		return err
+	}
+
+	// renders don't write the lock, so the project records the new template even if files have conflicts
+	return errors.Join(mergeUpgrade(baseDir, nextDir, srcPath), writeLock(srcPath, newTpl.Config))
+}
+
+func readUpgradeTree(n *Node) (*upgradeNode, error) {
+	if err := n.readPaths(); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for name, inserts := range n.inserts {
+		if len(inserts) > 0 {
+			values[name] = inserts[0].Value
+		}
+	}
+	for name, value := range n.values {
+		values[name] = value
+	}
+	delete(values, "path")
+
+	out := &upgradeNode{n.template.Name, n.id, values, nil}
+
+	var namespaces []string
+	for nspace := range n.template.Children {
+		namespaces = append(namespaces, nspace)
+	}
+	sort.Strings(namespaces)
+
+	for _, nspace := range namespaces {
+		children, err := n.Children(nspace)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, child := range children {
+			upgradeChild, err := readUpgradeTree(child)
+			if err != nil {
+				return nil, err
+			}
+			out.children = append(out.children, upgradeChild)
+		}
+	}
+
+	return out, nil
+}
+
+func renderUpgradeTree(tpl *template.Namespace, dir string, tree *upgradeNode) error {
+	root, err := NewWithOptions(tpl, dir, Options{render: true})
+	if err != nil {
+		return err
+	}
+
+	if err := root.SetValues(tree.values); err != nil {
+		return err
+	}
+	if err := createUpgradeChildren(root, tree); err != nil {
+		return err
+	}
+
+	return root.Flush()
+}
+
+func createUpgradeChildren(n *Node, tree *upgradeNode) error {
+	for _, child := range tree.children {
+		if _, ok := n.template.Children[child.namespace]; !ok {
+			continue // namespace is removed from the new template
+		}
+
+		created, err := n.CreateChild(child.namespace, child.id, child.values)
+		if err != nil {
+			return err
+		}
+		if err := createUpgradeChildren(created, child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mergeUpgrade applies changes between renders of the old and the new templates to the project.
+func mergeUpgrade(baseDir, nextDir, srcPath string) error {
+	names := make(map[string]struct{})
+	for _, dir := range []string{baseDir, nextDir} {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			name, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			names[name] = struct{}{}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var conflicts []string
+	for _, name := range sorted {
+		base, baseOk, err := readFileIfExists(filepath.Join(baseDir, name))
+		if err != nil {
+			return err
+		}
+		next, nextOk, err := readFileIfExists(filepath.Join(nextDir, name))
+		if err != nil {
+			return err
+		}
+		current, currentOk, err := readFileIfExists(filepath.Join(srcPath, name))
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(srcPath, name)
+		switch {
+		case !nextOk:
+			if currentOk && bytes.Equal(base, current) {
+				if err := os.Remove(path); err != nil {
+					return err
+				}
+			}
+			continue
+		case !currentOk:
+			if baseOk {
+				continue // deleted by hand
+			}
+		}
+
+		mergedLines, conflict := mergeLines(string(base), string(current), string(next))
+		merged := []byte(mergedLines)
+		if conflict {
+			conflicts = append(conflicts, path)
+		} else if filepath.Base(name) == "go.mod" {
+			merged, err = source.MergeGoMod(merged, nil)
+			if err != nil {
+				return err
+			}
+		}
+		if currentOk && bytes.Equal(merged, current) {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, merged, 0644); err != nil {
+			return err
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("maker: Upgrade: the project and the template changed the same lines, resolve conflicts in files: %s", strings.Join(conflicts, ", "))
+	}
+
+	return nil
+}
+
+func readFileIfExists(path string) ([]byte, bool, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return content, true, nil
+}
+
+// mergeLines is the three-way merge of lines, base is the render of the old template,
+// current is the project and next is the render of the new template.
+// Changes of the project and of the template to different lines of base are both applied.
+// If they change the same lines differently, both versions are written between conflict markers and conflict is true.
+func mergeLines(base, current, next string) (string, bool) {
+	baseLines := splitLines(base)
+	currentLines, nextLines := splitLines(current), splitLines(next)
+	currentHunks := diffHunks(baseLines, currentLines, matchLines(baseLines, currentLines))
+	nextHunks := diffHunks(baseLines, nextLines, matchLines(baseLines, nextLines))
+
+	var out []string
+	var conflict bool
+	position := 0
+	for len(currentHunks) > 0 || len(nextHunks) > 0 {
+		var group, currentGroup, nextGroup []upgradeHunk
+		group = append(group, takeFirstHunk(&currentHunks, &nextHunks, &currentGroup, &nextGroup))
+		start, end := group[0].start, group[0].end
+		for {
+			h, ok := takeOverlappingHunk(upgradeHunk{start, end, nil}, &currentHunks, &nextHunks, &currentGroup, &nextGroup)
+			if !ok {
+				break
+			}
+			start, end = min(start, h.start), max(end, h.end)
+		}
+
+		out = append(out, baseLines[position:start]...)
+		position = end
+
+		currentChunk := applyHunks(baseLines, start, end, currentGroup)
+		nextChunk := applyHunks(baseLines, start, end, nextGroup)
+		switch {
+		case len(currentGroup) == 0:
+			out = append(out, nextChunk...)
+		case len(nextGroup) == 0, slices.Equal(currentChunk, nextChunk):
+			out = append(out, currentChunk...)
+		default:
+			conflict = true
+			out = append(out, "<<<<<<< project\n")
+			out = append(out, withNewline(currentChunk)...)
+			out = append(out, "=======\n")
+			out = append(out, withNewline(nextChunk)...)
+			out = append(out, ">>>>>>> template\n")
+		}
+	}
+	out = append(out, baseLines[position:]...)
+
+	return strings.Join(out, ""), conflict
+}
+
+// upgradeHunk replaces lines of base from start to end with lines.
+type upgradeHunk struct {
+	start, end int
+	lines      []string
+}
+
+func (h upgradeHunk) overlaps(o upgradeHunk) bool {
+	if h.start == h.end && o.start == o.end {
+		return h.start == o.start // insertions at the same line
+	}
+	return h.start < o.end && o.start < h.end
+}
+
+// diffHunks returns changes from base to other by index of the matched line in other for every line in base.
+func diffHunks(base, other []string, match []int) []upgradeHunk {
+	var out []upgradeHunk
+	for i, j := 0, 0; ; {
+		anchor := i
+		for anchor < len(base) && match[anchor] == -1 {
+			anchor++
+		}
+		otherAnchor := len(other)
+		if anchor < len(base) {
+			otherAnchor = match[anchor]
+		}
+
+		if anchor > i || otherAnchor > j {
+			out = append(out, upgradeHunk{i, anchor, other[j:otherAnchor]})
+		}
+		if anchor == len(base) {
+			return out
+		}
+		i, j = anchor+1, otherAnchor+1
+	}
+}
+
+// takeFirstHunk removes the hunk nearest to the beginning of base, an insertion goes before a change at the same line.
+func takeFirstHunk(current, next *[]upgradeHunk, currentGroup, nextGroup *[]upgradeHunk) upgradeHunk {
+	from, group := current, currentGroup
+	if len(*current) == 0 || len(*next) > 0 && ((*next)[0].start < (*current)[0].start ||
+		(*next)[0].start == (*current)[0].start && (*next)[0].end < (*current)[0].end) {
+		from, group = next, nextGroup
+	}
+
+	h := (*from)[0]
+	*from = (*from)[1:]
+	*group = append(*group, h)
+	return h
+}
+
+// takeOverlappingHunk removes the first hunk of any side overlapping with lines of base from start to end.
+func takeOverlappingHunk(lines upgradeHunk, current, next *[]upgradeHunk, currentGroup, nextGroup *[]upgradeHunk) (upgradeHunk, bool) {
+	for _, side := range []struct{ from, group *[]upgradeHunk }{{current, currentGroup}, {next, nextGroup}} {
+		if len(*side.from) == 0 || !(*side.from)[0].overlaps(lines) {
+			continue
+		}
+		h := (*side.from)[0]
+		*side.from = (*side.from)[1:]
+		*side.group = append(*side.group, h)
+		return h, true
+	}
+	return upgradeHunk{}, false
+}
+
+// applyHunks returns lines of base from start to end with the hunks applied.
+func applyHunks(base []string, start, end int, hunks []upgradeHunk) []string {
+	var out []string
+	for _, h := range hunks {
+		out = append(out, base[start:h.start]...)
+		out = append(out, h.lines...)
+		start = h.end
+	}
+	return append(out, base[start:end]...)
+}
+
+// withNewline terminates the last line, so a conflict marker starts on its own line.
+func withNewline(lines []string) []string {
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		lines = append(slices.Clone(lines[:len(lines)-1]), lines[len(lines)-1]+"\n")
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// matchLines returns index of the matched line in b for every line in a or -1, using the longest common subsequence.
+func matchLines(a, b []string) []int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	out := make([]int, len(a))
+	for i := range out {
+		out[i] = -1
+	}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			out[i] = j
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return out
+}